@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+// ALPHABETA_WIN is the value of a won position for the alpha-beta search,
+// well above the -1 to 1 the evaluation is scaled to. Every move it takes to
+// get there makes a win worth a little less, so the quickest one is played.
+const ALPHABETA_WIN = 1000.0
+
+// alphaBetaSearch is a depth limited negamax search, which evaluates the
+// positions at its horizon with an Evaluator.
+type alphaBetaSearch struct {
+	evaluator *Evaluator
+	deadline  time.Time // The zero time means no deadline
+	nodes     int
+	aborted   bool
+
+	// The best move found in every position searched, tried first
+	// when the position is searched again at the next depth.
+	bestMoves map[GameState]Move
+}
+
+// negamax returns the value of the position in history for the player to
+// move, looking depth moves ahead, ply moves below the root, searching only
+// between alpha and beta. The moves it searches are made on history and
+// taken back again.
+func (search *alphaBetaSearch) negamax(history *GameHistory, depth, ply int, alpha, beta float64) float64 {
+	state := &history.GameState
+	search.nodes += 1
+	if search.nodes%1024 == 0 && !search.deadline.IsZero() && time.Now().After(search.deadline) {
+		search.aborted = true
+	}
+	if search.aborted {
+		return 0
+	}
+
+	if winner := history.Winner(); winner != EMPTY {
+		// Under the standard rules, only the player who just moved can
+		// have completed a line.
+		if winner == PlayerMark(state.Player) {
+			return ALPHABETA_WIN - float64(ply)
+		}
+		return -ALPHABETA_WIN + float64(ply)
+	}
+
+	moves := history.ValidMoves()
+	if len(moves) == 0 {
+		return 0
+	}
+
+	if depth == 0 {
+		return 2*search.evaluator.Evaluate(state) - 1
+	}
+
+	search.orderMoves(state, moves)
+
+	best := math.Inf(-1)
+	for _, move := range moves {
+		history.Make(move)
+		value := -search.negamax(history, depth-1, ply+1, -beta, -alpha)
+		history.Unmake()
+		if search.aborted {
+			return 0
+		}
+
+		if value > best {
+			best = value
+			search.bestMoves[*state] = *move
+		}
+
+		alpha = math.Max(alpha, best)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	return best
+}
+
+// orderMoves moves the best move found in state by an earlier search to the front.
+func (search *alphaBetaSearch) orderMoves(state *GameState, moves []*Move) {
+	if bestMove, ok := search.bestMoves[*state]; ok {
+		for i, move := range moves {
+			if *move == bestMove {
+				moves[0], moves[i] = moves[i], moves[0]
+				break
+			}
+		}
+	}
+}
+
+// AlphaBetaAnalysis searches state with iterative deepening alpha-beta,
+// evaluating positions with config.Evaluator (DefaultEvaluator if nil), until
+// config.MaxDepth is reached, config.ThinkTime runs out or no squares are
+// left to search any deeper. The moves of
+// config.Book are played without searching, and once few enough empty
+// squares are left, the endgame solver takes over instead.
+//
+// Candidates are scored from -1 (lost) to 1 (won), with the expected result
+// of the best move as its WinRate. Only the best move's score is exact, the
+// other scores are upper bounds.
+func AlphaBetaAnalysis(state *GameState, config SearchConfig) *Analysis {
+	start := time.Now()
+	if config.ThinkTime <= 0 && config.MaxDepth == 0 {
+		// Nothing would end the search, so it thinks as long as MonteCarloBot.
+		config.ThinkTime = time.Duration(TIME_TO_THINK * float64(time.Second))
+	}
+	if analysis := config.Book.Analysis(state); analysis != nil {
+		slog.Info("Alpha-beta bot played from the opening book", "search", analysis)
+		return analysis
+	}
+
+	if analysis := solveEndgame(state, config.SolverThreshold, config.deadline(start)); analysis != nil {
+		slog.Info("Alpha-beta bot solved the position", "search", analysis)
+		return analysis
+	}
+
+	search := &alphaBetaSearch{evaluator: config.Evaluator, bestMoves: make(map[GameState]Move)}
+	if search.evaluator == nil {
+		search.evaluator = DefaultEvaluator
+	}
+	if config.ThinkTime > 0 {
+		search.deadline = start.Add(config.ThinkTime)
+	}
+
+	moves := state.ValidMoves()
+	history := NewGameHistory(state)
+	var candidates []CandidateMove
+	completedDepth := 0
+
+	// The game is over once every empty square is filled, so searching
+	// deeper than that finds nothing new.
+	maxDepth := len(state.Board.ValidMoves())
+	if config.MaxDepth > 0 {
+		maxDepth = min(maxDepth, config.MaxDepth)
+	}
+	for depth := 1; depth <= maxDepth; depth++ {
+		search.orderMoves(state, moves)
+
+		scored := make([]CandidateMove, 0, len(moves))
+		alpha := math.Inf(-1)
+		for _, move := range moves {
+			history.Make(move)
+			value := -search.negamax(history, depth-1, 1, math.Inf(-1), -alpha)
+			history.Unmake()
+			if search.aborted {
+				break
+			}
+
+			scored = append(scored, CandidateMove{Move: *move, Score: value})
+			if value > alpha {
+				alpha = value
+				search.bestMoves[*state] = *move
+			}
+		}
+
+		if search.aborted {
+			break
+		}
+
+		candidates = scored
+		completedDepth = depth
+		if math.Abs(alpha) > 1 {
+			// The result is certain, looking further does not change it.
+			break
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	analysis := &Analysis{Candidates: candidates, TimeUsed: time.Since(start), Depth: completedDepth}
+	if len(candidates) > 0 {
+		switch best := candidates[0].Score; {
+		case best > 1:
+			analysis.Proven = resultName(SOLVED_WIN)
+		case best < -1:
+			analysis.Proven = resultName(SOLVED_LOSS)
+		}
+
+		for i := range candidates {
+			candidates[i].Score = math.Max(-1, math.Min(1, candidates[i].Score))
+		}
+		candidates[0].WinRate = (candidates[0].Score + 1) / 2
+
+		// Follow the best moves the search remembers for the principal variation.
+		line := *state
+		search.bestMoves[line] = candidates[0].Move
+		for move, ok := search.bestMoves[line]; ok && len(analysis.PrincipalVariation) < completedDepth; move, ok = search.bestMoves[line] {
+			analysis.PrincipalVariation = append(analysis.PrincipalVariation, move)
+			line.Play(&move)
+		}
+	}
+
+	slog.Info("Alpha-beta bot made its move", "search", analysis, "depth", analysis.Depth, "nodes", search.nodes)
+	return analysis
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestAlphaBetaFindsWinningMove(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won boards (0,0) and (1,1), and only needs to
+	// win board (2,2) (top left tile missing) to win the game.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+		state.Board[1][1][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][2][2] = PLAYER_1_CONTROLLED
+	state.LastMove = Move{0, 1, 2, 2}
+
+	analysis := AlphaBetaAnalysis(state, SearchConfig{MaxDepth: 3})
+	if move := analysis.BestMove(); *move != (Move{2, 2, 0, 0}) {
+		t.Error("Expected the search to find the winning move (2,2,0,0), it played", *move)
+	}
+
+	if analysis.Proven != "win" || analysis.Depth != 1 {
+		t.Error("Expected the search to prove the win at depth 1, got", analysis.Proven, "at depth", analysis.Depth)
+	}
+}
+
+func TestAlphaBetaDepth(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	analysis := AlphaBetaAnalysis(state, SearchConfig{MaxDepth: 3})
+	if analysis.Depth != 3 || len(analysis.PrincipalVariation) != 3 {
+		t.Error("Expected a depth 3 search with a 3 move principal variation, got depth", analysis.Depth, "and", analysis.PrincipalVariation)
+	}
+
+	if move := analysis.BestMove(); move.BoardX != 1 || move.BoardY != 1 {
+		t.Error("The search did not stick to the board it was forced to, it played", *move)
+	}
+
+	if len(analysis.Candidates) != 9 || analysis.Candidates[0].Move != analysis.PrincipalVariation[0] {
+		t.Error("Expected the 9 moves on board (1,1) ranked with the principal variation's move first, got", analysis.Candidates)
+	}
+}
+
+func TestAlphaBetaMisereHorizon(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won board (0,0) and can win the center board, which
+	// makes a line of two boards. Under the misère rules that line is a
+	// step towards losing, long after the end of a depth 1 search.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[1][1][0][0] = PLAYER_1_CONTROLLED
+	state.Board[1][1][0][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][1][1] = PLAYER_2_CONTROLLED
+	state.Board[2][1][1][1] = PLAYER_2_CONTROLLED
+	state.LastMove = Move{2, 1, 1, 1}
+	winsBoard := Move{1, 1, 0, 2}
+
+	if move := AlphaBetaAnalysis(state, SearchConfig{MaxDepth: 1}).BestMove(); *move != winsBoard {
+		t.Error("Expected the search to win the center board, it played", *move)
+	}
+
+	state.Rules = RULES_MISERE
+	if move := AlphaBetaAnalysis(state, SearchConfig{MaxDepth: 1}).BestMove(); *move == winsBoard {
+		t.Error("Expected the search to keep away from a line of boards under the misère rules, it played", *move)
+	}
+}
+
+func TestAlphaBetaStopsWithoutLimits(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	state := randomEndgame(random, 8)
+	for state.IsOver() {
+		state = randomEndgame(random, 8)
+	}
+
+	// Neither a depth nor a time limit is given, but no more than the
+	// empty squares can be searched.
+	start := time.Now()
+	analysis := AlphaBetaAnalysis(state, SearchConfig{})
+	if emptySquares := len(state.Board.ValidMoves()); analysis.Depth > emptySquares || analysis.BestMove() == nil {
+		t.Error("Expected a move from a search at most", emptySquares, "moves deep, got", analysis.BestMove(), "at depth", analysis.Depth)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("The search took", elapsed, "with", len(state.Board.ValidMoves()), "empty squares")
+	}
+}
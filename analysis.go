@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// CandidateMove holds what a search found out about one of the moves
+// that can be made in the analysed position. Rates are given from the
+// point of view of the player making the move.
+type CandidateMove struct {
+	Move     Move    `json:"move"`
+	Visits   int     `json:"visits"`
+	WinRate  float64 `json:"winRate"`
+	DrawRate float64 `json:"drawRate"`
+	LossRate float64 `json:"lossRate"`
+	Score    float64 `json:"score"` // The value the bot ranked the move by
+}
+
+// Analysis is the result of a search: the candidate moves, best first,
+// the line of play the search expects to follow and the number of
+// simulated games it was based on.
+type Analysis struct {
+	Candidates         []CandidateMove `json:"candidates"`
+	PrincipalVariation []Move          `json:"principalVariation"`
+	Playouts           int             `json:"playouts"`
+	TimeUsed           time.Duration   `json:"timeUsed"`
+	TreeSize           int             `json:"treeSize"`           // 0 for searches without a tree
+	Depth              int             `json:"depth,omitempty"`    // How many moves ahead an alpha-beta search looked
+	Proven             string          `json:"proven,omitempty"`   // "win", "draw" or "loss" if the result is certain
+	FromBook           bool            `json:"fromBook,omitempty"` // The move was taken from the opening book
+}
+
+// BestMove returns a pointer to the highest ranked move, or nil
+// if there were no moves to analyse.
+func (analysis *Analysis) BestMove() *Move {
+	if len(analysis.Candidates) == 0 {
+		return nil
+	}
+
+	return analysis.Candidates[0].Move.Copy()
+}
+
+// Confidence returns the expected result of the best move, from 0 (a
+// certain loss) to 1 (a certain win), counting a tie as half a win.
+func (analysis *Analysis) Confidence() float64 {
+	if len(analysis.Candidates) == 0 {
+		return 0
+	}
+
+	best := analysis.Candidates[0]
+	return best.WinRate + best.DrawRate/2
+}
+
+// LogValue implements slog.LogValuer, summarising the search
+// into the telemetry logged after every move.
+func (analysis *Analysis) LogValue() slog.Value {
+	var playoutsPerSecond float64
+	if analysis.TimeUsed > 0 {
+		playoutsPerSecond = float64(analysis.Playouts) / analysis.TimeUsed.Seconds()
+	}
+
+	attrs := []slog.Attr{
+		slog.Int("playouts", analysis.Playouts),
+		slog.Float64("playoutsPerSec", math.Round(playoutsPerSecond)),
+		slog.Duration("timeUsed", analysis.TimeUsed),
+		slog.Float64("confidence", analysis.Confidence()),
+	}
+
+	if analysis.TreeSize > 0 {
+		attrs = append(attrs, slog.Int("treeSize", analysis.TreeSize))
+	}
+
+	if analysis.Proven != "" {
+		attrs = append(attrs, slog.String("proven", analysis.Proven))
+	}
+
+	if analysis.FromBook {
+		attrs = append(attrs, slog.Bool("fromBook", true))
+	}
+
+	if best := analysis.BestMove(); best != nil {
+		attrs = append(attrs, slog.String("move", fmt.Sprintf("%d %d %d %d", best.BoardX, best.BoardY, best.TileX, best.TileY)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// String formats the analysis as a table, for printing on the command line.
+func (analysis *Analysis) String() string {
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "%d playouts\n", analysis.Playouts)
+	if analysis.Depth > 0 {
+		fmt.Fprintf(&buffer, "depth %d\n", analysis.Depth)
+	}
+	if analysis.FromBook {
+		buffer.WriteString("from the opening book\n")
+	}
+	if analysis.Proven != "" {
+		fmt.Fprintf(&buffer, "proven %s\n", analysis.Proven)
+	}
+	fmt.Fprintf(&buffer, "%-9s %8s %7s %7s %7s %8s\n", "move", "visits", "win", "draw", "loss", "score")
+	for _, candidate := range analysis.Candidates {
+		m := candidate.Move
+		fmt.Fprintf(&buffer, "%d %d %d %d   %8d %6.1f%% %6.1f%% %6.1f%% %8.4f\n", m.BoardX, m.BoardY, m.TileX, m.TileY,
+			candidate.Visits, candidate.WinRate*100, candidate.DrawRate*100, candidate.LossRate*100, candidate.Score)
+	}
+
+	buffer.WriteString("pv:")
+	for _, m := range analysis.PrincipalVariation {
+		fmt.Fprintf(&buffer, " (%d %d %d %d)", m.BoardX, m.BoardY, m.TileX, m.TileY)
+	}
+	buffer.WriteString("\n")
+
+	return buffer.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalysisString(t *testing.T) {
+	analysis := Analysis{
+		Candidates: []CandidateMove{
+			{Move{1, 1, 0, 0}, 30, 0.5, 0.25, 0.25, 0.625},
+			{Move{0, 0, 1, 1}, 10, 0.1, 0.2, 0.7, 0.2},
+		},
+		PrincipalVariation: []Move{{1, 1, 0, 0}, {0, 0, 2, 2}},
+		Playouts:           40,
+	}
+
+	if *analysis.BestMove() != (Move{1, 1, 0, 0}) {
+		t.Error("BestMove() should return the first candidate move!")
+	}
+
+	output := analysis.String()
+	for _, expected := range []string{"40 playouts", "1 1 0 0", "50.0%", "pv: (1 1 0 0) (0 0 2 2)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the analysis to contain %q:\n%s", expected, output)
+		}
+	}
+
+	var empty Analysis
+	if empty.BestMove() != nil {
+		t.Error("An empty analysis should not have a best move!")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ANSI escape codes for the terminal renderer.
+const (
+	ANSI_RESET     = "\x1b[0m"
+	ANSI_BOLD      = "\x1b[1m"
+	ANSI_DIM       = "\x1b[2m"
+	ANSI_UNDERLINE = "\x1b[4m"
+	ANSI_PLAYER_1  = "\x1b[1;31m"
+	ANSI_PLAYER_2  = "\x1b[1;34m"
+	ANSI_OVERLAY   = "\x1b[32m"
+	ANSI_HIGHLIGHT = "\x1b[43m"
+)
+
+// String returns the board as three lines of three squares.
+func (board *TictactoeBoard) String() string {
+	var builder strings.Builder
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			builder.WriteByte(byte(board[x][y]))
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+// String returns the board as RenderText draws it without colors.
+func (board *UltimateBoard) String() string {
+	return RenderText(board, RenderOptions{}, false)
+}
+
+// isDead returns true if no more moves can be made on the board at x, y,
+// because it has been won or is full.
+func (board *UltimateBoard) isDead(x, y int) bool {
+	return board[x][y].HasWinner() != EMPTY || len(board[x][y].ValidMoves(x, y)) == 0
+}
+
+// RenderText draws board for a terminal as a 9x9 grid, with the rows and
+// columns numbered from 0 to 8, so that the square in row r and column c
+// is the move "r/3 c/3 r%3 c%3". The empty squares of the boards the next
+// player may play on (see RenderOptions.LastMove) are drawn as '.', other
+// empty squares as '-', or left blank on boards which are won or full.
+// Candidates are drawn over their squares as their expected result, in
+// percent. Pieces on boards which are won are drawn in lower case, and
+// the Cursor in brackets.
+//
+// With colors, X and O are drawn in red and blue, the boards the next
+// player may play on are highlighted, Played is underlined and boards
+// which are won or full are dimmed.
+func RenderText(board *UltimateBoard, options RenderOptions, colors bool) string {
+	playable := make(map[[2]int]bool)
+	for _, highlighted := range highlightedBoards(options.position(board)) {
+		playable[highlighted] = true
+	}
+
+	overlay := make(map[Move]float64)
+	for _, candidate := range options.Candidates {
+		overlay[candidate.Move] = candidate.WinRate + candidate.DrawRate/2
+	}
+
+	header := "  "
+	for column := 0; column < 9; column++ {
+		if column%3 == 0 {
+			header += "   "
+		}
+		header += fmt.Sprintf(" %d ", column)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(strings.TrimRight(header, " ") + "\n")
+
+	border := "   +" + strings.Repeat(strings.Repeat("-", 11)+"+", 3) + "\n"
+	for row := 0; row < 9; row++ {
+		if row%3 == 0 {
+			builder.WriteString(border)
+		}
+
+		fmt.Fprintf(&builder, " %d |", row)
+		for column := 0; column < 9; column++ {
+			if column%3 == 0 {
+				builder.WriteString(" ")
+			}
+
+			move := Move{row / 3, column / 3, row % 3, column % 3}
+			builder.WriteString(renderSquare(board, move, options, playable, overlay, colors))
+
+			if column%3 == 2 {
+				builder.WriteString(" |")
+			}
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString(border)
+
+	return builder.String()
+}
+
+// renderSquare returns the three characters RenderText draws for the square
+// of move, with the escape codes to color it if colors is set.
+func renderSquare(board *UltimateBoard, move Move, options RenderOptions, playable map[[2]int]bool, overlay map[Move]float64, colors bool) string {
+	square := board[move.BoardX][move.BoardY][move.TileX][move.TileY]
+	won := board[move.BoardX][move.BoardY].HasWinner() != EMPTY
+	expected, hasOverlay := overlay[move]
+
+	var text, style string
+	switch {
+	case square != EMPTY:
+		mark := string(rune(square))
+		if won {
+			mark = strings.ToLower(mark)
+		}
+		text = " " + mark + " "
+		style = ANSI_PLAYER_1
+		if square == PLAYER_2_CONTROLLED {
+			style = ANSI_PLAYER_2
+		}
+	case hasOverlay:
+		text = fmt.Sprintf("%3.0f", 100*expected)
+		style = ANSI_OVERLAY
+		if len(options.Candidates) > 0 && options.Candidates[0].Move == move {
+			style += ANSI_BOLD
+		}
+	case playable[[2]int{move.BoardX, move.BoardY}]:
+		text = " . "
+	case board.isDead(move.BoardX, move.BoardY):
+		text = "   "
+	default:
+		text = " - "
+	}
+
+	if options.Cursor != nil && *options.Cursor == move {
+		if hasOverlay && square == EMPTY {
+			text = " . "
+		}
+		text = "[" + text[1:2] + "]"
+	}
+
+	if !colors {
+		return text
+	}
+
+	if board.isDead(move.BoardX, move.BoardY) {
+		style += ANSI_DIM
+	}
+	if playable[[2]int{move.BoardX, move.BoardY}] {
+		style += ANSI_HIGHLIGHT
+	}
+	if options.Played != nil && *options.Played == move {
+		style += ANSI_UNDERLINE
+	}
+
+	if style == "" {
+		return text
+	}
+	return style + text + ANSI_RESET
+}
+
+// useColors returns true if RenderText should draw colors on file: if it is
+// a terminal rather than a file or a pipe, and NO_COLOR is not set.
+func useColors(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+}
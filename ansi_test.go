@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTictactoeBoardString(t *testing.T) {
+	board := TictactoeBoard{{PLAYER_1_CONTROLLED, EMPTY, EMPTY}, {EMPTY, PLAYER_2_CONTROLLED, EMPTY}, {EMPTY, EMPTY, PLAYER_1_CONTROLLED}}
+	if board.String() != "X--\n-O-\n--X\n" {
+		t.Errorf("Unexpected board %q", board.String())
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	state := NewGame()
+	for _, move := range []Move{{0, 0, 1, 1}, {1, 1, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 1}, {0, 1, 0, 0}, {0, 0, 0, 2}, {0, 2, 2, 2}, {2, 2, 0, 0}, {0, 0, 2, 2}} {
+		state.Play(&move)
+	}
+
+	candidates := []CandidateMove{{Move: Move{2, 2, 1, 1}, WinRate: 0.55, DrawRate: 0.1}, {Move: Move{2, 2, 0, 1}, WinRate: 0.3}}
+	options := RenderOptions{LastMove: &state.LastMove, Played: &state.LastMove, Candidates: candidates}
+
+	expected := `      0  1  2     3  4  5     6  7  8
+   +-----------+-----------+-----------+
+ 0 |  x  o  o  |  X  -  -  |  -  -  -  |
+ 1 |     x     |  -  -  -  |  -  -  -  |
+ 2 |        x  |  -  -  -  |  -  -  X  |
+   +-----------+-----------+-----------+
+ 3 |  -  -  -  |  O  -  -  |  -  -  -  |
+ 4 |  -  -  -  |  -  -  -  |  -  -  -  |
+ 5 |  -  -  -  |  -  -  -  |  -  -  -  |
+   +-----------+-----------+-----------+
+ 6 |  -  -  -  |  -  -  -  |  O  30 .  |
+ 7 |  -  -  -  |  -  -  -  |  .  60 .  |
+ 8 |  -  -  -  |  -  -  -  |  .  .  .  |
+   +-----------+-----------+-----------+
+`
+	if text := RenderText(&state.Board, options, false); text != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, text)
+	}
+
+	colored := RenderText(&state.Board, options, true)
+	for _, code := range []string{ANSI_PLAYER_1, ANSI_PLAYER_2, ANSI_DIM, ANSI_HIGHLIGHT, ANSI_UNDERLINE, ANSI_OVERLAY + ANSI_BOLD} {
+		if !strings.Contains(colored, code) {
+			t.Errorf("Expected the escape code %q in the colored board", code)
+		}
+	}
+
+	// Without the escape codes, the colored board is the same.
+	for _, code := range []string{ANSI_RESET, ANSI_BOLD, ANSI_DIM, ANSI_UNDERLINE, ANSI_PLAYER_1, ANSI_PLAYER_2, ANSI_OVERLAY, ANSI_HIGHLIGHT} {
+		colored = strings.ReplaceAll(colored, code, "")
+	}
+	if colored != expected {
+		t.Errorf("Expected the colored board to read\n%s\ngot\n%s", expected, colored)
+	}
+
+	if state.Board.String() != RenderText(&state.Board, RenderOptions{}, false) {
+		t.Error("UltimateBoard.String does not render the board without colors")
+	}
+}
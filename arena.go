@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Bot decides which move to make in a position.
+type Bot func(state *GameState) *Move
+
+// An Analyst searches a position and reports on the moves it considered,
+// best first.
+type Analyst func(state *GameState) *Analysis
+
+// Analysts holds the bots that can be picked by name, each created from the
+// SearchConfig to use (which bots without a search simply ignore).
+var Analysts = map[string]func(config SearchConfig) Analyst{
+	"random": func(config SearchConfig) Analyst {
+		return func(state *GameState) *Analysis {
+			return moveAnalysis(UniformPolicy{}.ChooseMove(state, state.ValidMoves()))
+		}
+	},
+	"montecarlo": func(config SearchConfig) Analyst {
+		return func(state *GameState) *Analysis {
+			return MonteCarloAnalysis(state, config)
+		}
+	},
+	"uct": func(config SearchConfig) Analyst {
+		config.RaveEquivalence = 0
+		config.Policy = nil
+		config.Evaluator = nil
+		return treeSearchAnalyst(config)
+	},
+	"heavy": func(config SearchConfig) Analyst {
+		if config.Policy == nil {
+			config.Policy = EpsilonGreedyPolicy{Epsilon: DEFAULT_EPSILON, Greedy: HeavyPolicy{}}
+		}
+		return treeSearchAnalyst(config)
+	},
+	"policy": func(config SearchConfig) Analyst {
+		// Plays the move its playout policy picks, without searching.
+		policy := config.Policy
+		if policy == nil {
+			policy = EpsilonGreedyPolicy{Epsilon: DEFAULT_EPSILON, Greedy: HeavyPolicy{}}
+		}
+		return func(state *GameState) *Analysis {
+			return moveAnalysis(policy.ChooseMove(state, state.ValidMoves()))
+		}
+	},
+	"alphabeta": func(config SearchConfig) Analyst {
+		return func(state *GameState) *Analysis {
+			return AlphaBetaAnalysis(state, config)
+		}
+	},
+	"puct": func(config SearchConfig) Analyst {
+		return func(state *GameState) *Analysis {
+			return PUCTAnalysis(state, config)
+		}
+	},
+	"eval": func(config SearchConfig) Analyst {
+		if config.Evaluator == nil {
+			config.Evaluator = DefaultEvaluator
+		}
+		return treeSearchAnalyst(config)
+	},
+	"rave": func(config SearchConfig) Analyst {
+		if config.RaveEquivalence == 0 {
+			config.RaveEquivalence = DEFAULT_RAVE_EQUIVALENCE
+		}
+		return treeSearchAnalyst(config)
+	},
+}
+
+// Bots holds the Analysts as Bots, which play the move ranked best.
+var Bots = analystBots()
+
+// analystBots returns a Bot for every one of the Analysts.
+func analystBots() map[string]func(config SearchConfig) Bot {
+	bots := make(map[string]func(config SearchConfig) Bot)
+	for name, newAnalyst := range Analysts {
+		newAnalyst := newAnalyst
+		bots[name] = func(config SearchConfig) Bot {
+			return analystBot(newAnalyst(config))
+		}
+	}
+
+	return bots
+}
+
+// BotNames returns the names of the bots in Bots, sorted.
+func BotNames() []string {
+	names := make([]string, 0, len(Bots))
+	for name := range Bots {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// moveAnalysis returns the Analysis of a bot which picks move without searching.
+func moveAnalysis(move *Move) *Analysis {
+	return &Analysis{Candidates: []CandidateMove{{Move: *move}}, PrincipalVariation: []Move{*move}}
+}
+
+// treeSearchAnalyst returns an Analyst searching with a new SearchTree for every move.
+func treeSearchAnalyst(config SearchConfig) Analyst {
+	return func(state *GameState) *Analysis {
+		return TreeSearchAnalysis(state, config)
+	}
+}
+
+// PlayGame plays a game between player1 (X) and player2 (O), returning
+// the winner (PLAYER_1_CONTROLLED, PLAYER_2_CONTROLLED or EMPTY for a tie).
+func PlayGame(player1, player2 Bot) int {
+	state := NewGame()
+	for !state.IsOver() {
+		if state.Player == 1 {
+			state.Play(player1(state))
+		} else {
+			state.Play(player2(state))
+		}
+	}
+
+	return state.Winner()
+}
+
+// ArenaResult counts the results of the games between two bots, from the
+// point of view of the first bot.
+type ArenaResult struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games returns the number of games played.
+func (result ArenaResult) Games() int {
+	return result.Wins + result.Draws + result.Losses
+}
+
+// Score returns the share of the points the first bot won,
+// where a win is worth 1 point and a tie half a point.
+func (result ArenaResult) Score() float64 {
+	return (float64(result.Wins) + float64(result.Draws)/2) / float64(result.Games())
+}
+
+// String formats the result, with the 95% confidence interval of the score.
+func (result ArenaResult) String() string {
+	score := result.Score()
+	games := float64(result.Games())
+
+	// Variance of the per-game points around the mean score.
+	variance := (float64(result.Wins)*(1-score)*(1-score) + float64(result.Draws)*(0.5-score)*(0.5-score) + float64(result.Losses)*score*score) / games
+	margin := 1.96 * math.Sqrt(variance/games)
+
+	return fmt.Sprintf("+%d =%d -%d, score %.1f%% ± %.1f%%", result.Wins, result.Draws, result.Losses, score*100, margin*100)
+}
+
+// PlayArena plays games games between bot and opponent, taking turns at
+// making the first move, spread over all CPUs.
+func PlayArena(bot, opponent Bot, games int) ArenaResult {
+	return playArena(games, func(first bool) int {
+		if first {
+			return PlayGame(bot, opponent)
+		}
+		return PlayGame(opponent, bot)
+	})
+}
+
+// playArena plays games games with play, which plays a single game and
+// returns its winner, with the bot making the first move if first is set.
+func playArena(games int, play func(first bool) int) ArenaResult {
+	var result ArenaResult
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	gameNumbers := make(chan int)
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for game := range gameNumbers {
+				// Count the result from the point of view of bot.
+				winner, botMark := play(game%2 == 0), PLAYER_1_CONTROLLED
+				if game%2 != 0 {
+					botMark = PLAYER_2_CONTROLLED
+				}
+
+				lock.Lock()
+				switch winner {
+				case EMPTY:
+					result.Draws += 1
+				case botMark:
+					result.Wins += 1
+				default:
+					result.Losses += 1
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for game := 0; game < games; game++ {
+		gameNumbers <- game
+	}
+	close(gameNumbers)
+	wg.Wait()
+
+	return result
+}
+
+// RunArena plays games games between the two bots named in names,
+// separated by a comma (e.g. "rave,uct" or "uct/lcb,uct/most-visits"),
+// and returns the result.
+func RunArena(names string, config SearchConfig, games int) (string, error) {
+	botNames := strings.Split(names, ",")
+
+	analysts, err := newAnalysts(botNames, config)
+	if err != nil {
+		return "", err
+	}
+
+	bots := [2]Bot{analystBot(analysts[0]), analystBot(analysts[1])}
+	result := PlayArena(bots[0], bots[1], games)
+	return fmt.Sprintf("%s vs %s: %s\n", botNames[0], botNames[1], result), nil
+}
+
+// RunVariantArena plays games games between the two GameBots named in
+// names, separated by a comma, on the variant (see NewVariant), and
+// returns the result.
+func RunVariantArena(variant, names string, config SearchConfig, games int) (string, error) {
+	start, err := NewVariant(variant)
+	if err != nil {
+		return "", err
+	}
+
+	botNames := strings.Split(names, ",")
+	if len(botNames) != 2 {
+		return "", fmt.Errorf("expected two bots separated by a comma, got %q", names)
+	}
+
+	var bots [2]GameBot
+	for i, name := range botNames {
+		newBot, ok := GameBots[name]
+		if !ok {
+			return "", fmt.Errorf("unknown bot %q for variants, expected random or montecarlo", name)
+		}
+		bots[i] = newBot(config)
+	}
+
+	result := playArena(games, func(first bool) int {
+		if first {
+			return PlayGameOf(start.Clone(), bots[0], bots[1])
+		}
+		return PlayGameOf(start.Clone(), bots[1], bots[0])
+	})
+	return fmt.Sprintf("%s vs %s on %s: %s\n", botNames[0], botNames[1], variant, result), nil
+}
+
+// newAnalysts returns the two Analysts named in botNames. A name may be
+// followed by the SelectionStrategy the bot should use, e.g. "uct/lcb",
+// to compare strategies against each other.
+func newAnalysts(botNames []string, config SearchConfig) ([2]Analyst, error) {
+	var analysts [2]Analyst
+	if len(botNames) != 2 {
+		return analysts, fmt.Errorf("expected two bots separated by a comma, got %q", strings.Join(botNames, ","))
+	}
+
+	for i, spec := range botNames {
+		name, strategyName, withStrategy := strings.Cut(spec, "/")
+		botConfig := config
+		if withStrategy {
+			strategy, err := ParseSelectionStrategy(strategyName)
+			if err != nil {
+				return analysts, err
+			}
+			botConfig.Selection = strategy
+		}
+
+		newAnalyst, ok := Analysts[name]
+		if !ok {
+			return analysts, fmt.Errorf("unknown bot %q, expected one of %s", name, strings.Join(BotNames(), ", "))
+		}
+		analysts[i] = newAnalyst(botConfig)
+	}
+
+	return analysts, nil
+}
+
+// analystBot returns a Bot playing the move analyst ranks best.
+func analystBot(analyst Analyst) Bot {
+	return func(state *GameState) *Move {
+		return analyst(state).BestMove()
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlayGame(t *testing.T) {
+	random := Bots["random"](testSearchConfig)
+
+	for i := 0; i < 20; i++ {
+		winner := PlayGame(random, random)
+		if winner != EMPTY && winner != PLAYER_1_CONTROLLED && winner != PLAYER_2_CONTROLLED {
+			t.Fatal("PlayGame returned an unknown winner", winner)
+		}
+	}
+}
+
+func TestPlayArena(t *testing.T) {
+	config := testSearchConfig
+	config.MaxPlayouts = 100
+
+	result := PlayArena(Bots["rave"](config), Bots["random"](config), 6)
+	if result.Games() != 6 {
+		t.Error("Expected 6 games to be played, got", result.Games())
+	}
+
+	if result.Score() <= 0.5 {
+		t.Error("Expected the rave bot to beat the random bot, got", result)
+	}
+}
+
+func TestArenaResultString(t *testing.T) {
+	result := ArenaResult{Wins: 6, Draws: 2, Losses: 2}
+	if result.Score() != 0.7 {
+		t.Error("Expected a score of 0.7, got", result.Score())
+	}
+
+	if output := result.String(); !strings.HasPrefix(output, "+6 =2 -2, score 70.0% ± ") {
+		t.Error("Unexpected result string", output)
+	}
+}
+
+func TestRunArenaUnknownBot(t *testing.T) {
+	if _, err := RunArena("rave,deepblue", testSearchConfig, 1); err == nil {
+		t.Error("Expected an error for an unknown bot!")
+	}
+
+	if _, err := RunArena("rave", testSearchConfig, 1); err == nil {
+		t.Error("Expected an error when only naming one bot!")
+	}
+
+	if _, err := RunArena("uct/best,uct", testSearchConfig, 1); err == nil {
+		t.Error("Expected an error for an unknown selection strategy!")
+	}
+}
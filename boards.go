@@ -66,12 +66,18 @@ func (board *TictactoeBoard) Clear() {
 // ValidMoves returns a slice of *Move, containing all the "legal"
 // moves that can still be made on the Tic-tac-toe board in question.
 func (board *TictactoeBoard) ValidMoves(boardX, boardY int) []*Move {
-	validMoves := make([]*Move, 0, 9) // Pre-allocate capacity for up to 9 moves (the max)
 	if board.HasWinner() != EMPTY {
 		// If the board has already been won no moves can be made on it.
 		return nil
 	}
 
+	return board.emptyTiles(boardX, boardY)
+}
+
+// emptyTiles returns a slice of *Move, containing a move on every empty
+// tile of the board, whether or not it has been won.
+func (board *TictactoeBoard) emptyTiles(boardX, boardY int) []*Move {
+	validMoves := make([]*Move, 0, 9) // Pre-allocate capacity for up to 9 moves (the max)
 	for i := 0; i < 3; i++ {
 		for j := 0; j < 3; j++ {
 			if board[i][j] == EMPTY {
@@ -84,46 +90,17 @@ func (board *TictactoeBoard) ValidMoves(boardX, boardY int) []*Move {
 }
 
 // HasWinner uses similar logic as in TictactoeBoard:HasWinner to
-// check whether either player has already won the ultimate board.
+// check whether either player has already won the ultimate board,
+// checking who has won each of its boards only once.
 func (board *UltimateBoard) HasWinner() int {
+	var winners TictactoeBoard
 	for i := 0; i < 3; i++ {
-		if board[i][0].HasWinner() != EMPTY && board[i][0].HasWinner() == board[i][1].HasWinner() && board[i][0].HasWinner() == board[i][2].HasWinner() {
-			/*
-			   |X| | |      | |X| |       | | |X|
-			   |X| | |  OR  | |X| |  OR   | | |X|
-			   |X| | |      | |X| |       | | |X|
-			*/
-			return board[i][0].HasWinner()
-		}
-
-		if board[0][i].HasWinner() != EMPTY && board[0][i].HasWinner() == board[1][i].HasWinner() && board[0][i].HasWinner() == board[2][i].HasWinner() {
-			/*
-			   | | | |      | | | |       |X|X|X|
-			   | | | |  OR  |X|X|X|  OR   | | | |
-			   |X|X|X|      | | | |       | | | |
-			*/
-			return board[0][i].HasWinner()
+		for j := 0; j < 3; j++ {
+			winners[i][j] = board[i][j].HasWinner()
 		}
 	}
 
-	// Check diagonals
-	if board[0][0].HasWinner() != EMPTY && board[0][0].HasWinner() == board[1][1].HasWinner() && board[0][0].HasWinner() == board[2][2].HasWinner() {
-		/*
-		   | | |X|
-		   | |X| |
-		   |X| | |
-		*/
-		return board[0][0].HasWinner()
-	} else if board[2][0].HasWinner() != EMPTY && board[2][0].HasWinner() == board[1][1].HasWinner() && board[2][0].HasWinner() == board[0][2].HasWinner() {
-		/*
-		   |X| | |
-		   | |X| |
-		   | | |X|
-		*/
-		return board[2][0].HasWinner()
-	}
-
-	return EMPTY // Winning conditions not satisfied for either player.
+	return winners.HasWinner()
 }
 
 // Clear clears the UltimateBoard, setting every
@@ -195,3 +172,100 @@ func (board *UltimateBoard) Copy() *UltimateBoard {
 	boardCopy := originalBoard
 	return &boardCopy
 }
+
+// BoardStatus caches who has won each board of an UltimateBoard, how many
+// of its tiles are filled and who has won the UltimateBoard itself. Update
+// keeps it up to date one move at a time, by only checking the lines
+// through the tile played, which saves playouts from calling HasWinner on
+// every board after every move.
+type BoardStatus struct {
+	Boards TictactoeBoard // Who has won each board, EMPTY if nobody has
+	Filled [3][3]int      // How many tiles of each board are filled
+	Winner int            // Who has won the UltimateBoard, EMPTY if nobody has
+}
+
+// NewBoardStatus returns the status of board, checking it from scratch.
+func NewBoardStatus(board *UltimateBoard) BoardStatus {
+	var status BoardStatus
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			status.Boards[i][j] = board[i][j].HasWinner()
+			for k := 0; k < 3; k++ {
+				for l := 0; l < 3; l++ {
+					if board[i][j][k][l] != EMPTY {
+						status.Filled[i][j] += 1
+					}
+				}
+			}
+		}
+	}
+
+	status.Winner = status.Boards.HasWinner()
+	return status
+}
+
+// Update updates the status once a mark has been put on the tile of board
+// which move plays on, which must have been empty.
+func (status *BoardStatus) Update(board *UltimateBoard, move *Move) {
+	x, y := move.BoardX, move.BoardY
+	mark := board[x][y][move.TileX][move.TileY]
+	status.Filled[x][y] += 1
+
+	if status.Boards[x][y] != EMPTY || !completesLine(&board[x][y], move.TileX, move.TileY, mark) {
+		return
+	}
+
+	// Under the most-boards rules, both players may complete lines of
+	// boards, so the UltimateBoard is checked like HasWinner does.
+	status.Boards[x][y] = mark
+	status.Winner = status.Boards.HasWinner()
+}
+
+// IsOpen returns true if moves can still be made on board (x, y): it is
+// neither won nor full.
+func (status *BoardStatus) IsOpen(x, y int) bool {
+	return status.Boards[x][y] == EMPTY && status.Filled[x][y] < 9
+}
+
+// ValidMoves returns the same moves as state.ValidMoves, in the same order,
+// when status is the status of state.Board.
+func (status *BoardStatus) ValidMoves(state *GameState) []*Move {
+	x, y := state.LastMove.TileX, state.LastMove.TileY
+	forced := state.Rules&RULES_FREE_MOVES == 0 && x != -1 && status.IsOpen(x, y)
+	var open [3][3]bool
+	count := 0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			open[i][j] = status.IsOpen(i, j) && (!forced || (i == x && j == y))
+			if open[i][j] {
+				count += 9 - status.Filled[i][j]
+			}
+		}
+	}
+
+	// The moves point into a single slice, rather than being allocated
+	// one by one.
+	moves := make([]Move, 0, count)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if !open[i][j] {
+				continue
+			}
+
+			for k := 0; k < 3; k++ {
+				for l := 0; l < 3; l++ {
+					if state.Board[i][j][k][l] == EMPTY {
+						moves = append(moves, Move{i, j, k, l})
+					}
+				}
+			}
+		}
+	}
+
+	validMoves := make([]*Move, len(moves))
+	for i := range moves {
+		validMoves[i] = &moves[i]
+	}
+
+	return validMoves
+}
@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+)
 
 func TestTicTacToeBordHasWinner(t *testing.T) {
 	var board TictactoeBoard
@@ -179,6 +182,42 @@ func TestUltimateBoardAllPossibleMoves(t *testing.T) {
 	}
 }
 
+func TestBoardStatus(t *testing.T) {
+	for _, rules := range []Rules{0, RULES_MISERE, RULES_MOST_BOARDS, RULES_FREE_MOVES} {
+		for game := 0; game < 50; game++ {
+			state := NewGame()
+			state.Rules = rules
+			status := NewBoardStatus(&state.Board)
+
+			for {
+				if status != NewBoardStatus(&state.Board) {
+					t.Fatalf("%s: the status was not kept up to date:\n%s", rules, FormatPosition(state))
+				}
+				if winner := rules.StatusWinner(&status); winner != state.Winner() {
+					t.Fatalf("%s: expected %c to win, got %c", rules, state.Winner(), winner)
+				}
+
+				moves, expected := status.ValidMoves(state), state.ValidMoves()
+				if len(moves) != len(expected) {
+					t.Fatalf("%s: expected %d moves, got %d", rules, len(expected), len(moves))
+				}
+				for i := range moves {
+					if *moves[i] != *expected[i] {
+						t.Fatalf("%s: expected the move %v, got %v", rules, *expected[i], *moves[i])
+					}
+				}
+
+				if state.IsOver() {
+					break
+				}
+				move := moves[rand.Intn(len(moves))]
+				state.Play(move)
+				status.Update(&state.Board, move)
+			}
+		}
+	}
+}
+
 func BenchmarkHasWinnerEmptyBoard(b *testing.B) {
 	var board TictactoeBoard
 
@@ -220,3 +259,102 @@ func BenchmarkMoveCreate(b *testing.B) {
 		_ = Move{1, 1, 1, 1}
 	}
 }
+
+func BenchmarkHasWinnerFullUltimateBoard(b *testing.B) {
+	// A tie, on which every line of boards has to be checked.
+	board := randomTie()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = board.HasWinner()
+	}
+}
+
+// legacyHasWinner is UltimateBoard.HasWinner as it was before BoardStatus,
+// checking who has won a board again for every line through it.
+func legacyHasWinner(board *UltimateBoard) int {
+	for i := 0; i < 3; i++ {
+		if board[i][0].HasWinner() != EMPTY && board[i][0].HasWinner() == board[i][1].HasWinner() && board[i][0].HasWinner() == board[i][2].HasWinner() {
+			return board[i][0].HasWinner()
+		}
+
+		if board[0][i].HasWinner() != EMPTY && board[0][i].HasWinner() == board[1][i].HasWinner() && board[0][i].HasWinner() == board[2][i].HasWinner() {
+			return board[0][i].HasWinner()
+		}
+	}
+
+	if board[0][0].HasWinner() != EMPTY && board[0][0].HasWinner() == board[1][1].HasWinner() && board[0][0].HasWinner() == board[2][2].HasWinner() {
+		return board[0][0].HasWinner()
+	} else if board[2][0].HasWinner() != EMPTY && board[2][0].HasWinner() == board[1][1].HasWinner() && board[2][0].HasWinner() == board[0][2].HasWinner() {
+		return board[2][0].HasWinner()
+	}
+
+	return EMPTY
+}
+
+// legacyValidMoves is validMoves as it was before BoardStatus, listing the
+// moves on the forced board to tell whether it is full.
+func legacyValidMoves(board *UltimateBoard, previousMove *Move) []*Move {
+	x, y := previousMove.TileX, previousMove.TileY
+	if (x == -1 && y == -1) || board[x][y].HasWinner() != EMPTY || len(board[x][y].ValidMoves(x, y)) == 0 {
+		return board.ValidMoves()
+	}
+
+	return board[x][y].ValidMoves(x, y)
+}
+
+func TestLegacyPlayout(t *testing.T) {
+	// The copies of the old code agree with the code benchmarked against them.
+	for game := 0; game < 100; game++ {
+		state := NewGame()
+		for !state.IsOver() {
+			if legacyHasWinner(&state.Board) != state.Board.HasWinner() {
+				t.Fatal("The winners differ on", FormatPosition(state))
+			}
+
+			moves := legacyValidMoves(&state.Board, &state.LastMove)
+			if len(moves) != len(state.ValidMoves()) {
+				t.Fatal("The valid moves differ on", FormatPosition(state))
+			}
+			state.Play(moves[rand.Intn(len(moves))])
+		}
+	}
+}
+
+// BenchmarkPlayoutRecomputed plays random games the way simulate did before
+// BoardStatus, with copies of the old code checking every board again after
+// every move. Compare it with BenchmarkPlayoutCached for the speedup.
+func BenchmarkPlayoutRecomputed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		state := NewGame()
+		for legacyHasWinner(&state.Board) == EMPTY {
+			moves := legacyValidMoves(&state.Board, &state.LastMove)
+			if len(moves) == 0 {
+				break
+			}
+			state.Play(moves[rand.Intn(len(moves))])
+		}
+	}
+}
+
+// BenchmarkPlayoutCached plays random games with simulate, which keeps the
+// status of the board up to date instead.
+func BenchmarkPlayoutCached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		simulate(NewGame(), nil, nil)
+	}
+}
+
+// randomTie returns a board on which a random game has ended in a tie.
+func randomTie() UltimateBoard {
+	for {
+		state := NewGame()
+		for !state.IsOver() {
+			moves := state.ValidMoves()
+			state.Play(moves[rand.Intn(len(moves))])
+		}
+		if state.Winner() == EMPTY {
+			return state.Board
+		}
+	}
+}
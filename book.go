@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BOOK_VERSION is the version of the opening book file format written by Save.
+const BOOK_VERSION = 2
+
+// BookEntry is the move an opening book plays in a position, with the
+// statistics of the search that picked it.
+type BookEntry struct {
+	Move     Move
+	Visits   int
+	WinRate  float64
+	DrawRate float64
+}
+
+// Book is an opening book: the moves to play in early positions, found by
+// searching them offline. Positions are stored in their Canonical form, so
+// a single entry covers all symmetries of a position.
+type Book struct {
+	entries map[GameState]BookEntry
+}
+
+// NewBook returns an empty Book.
+func NewBook() *Book {
+	return &Book{entries: make(map[GameState]BookEntry)}
+}
+
+// Len returns the number of positions in the book.
+func (book *Book) Len() int {
+	return len(book.entries)
+}
+
+// Add adds entry for state to the book, with its move given for state.
+func (book *Book) Add(state *GameState, entry BookEntry) {
+	key, symmetry := Canonical(state)
+	entry.Move = symmetry.Move(entry.Move)
+	book.entries[key] = entry
+}
+
+// Lookup returns the book's entry for state, with its move given for state.
+func (book *Book) Lookup(state *GameState) (BookEntry, bool) {
+	key, symmetry := Canonical(state)
+	entry, ok := book.entries[key]
+	entry.Move = symmetry.Inverse().Move(entry.Move)
+	return entry, ok
+}
+
+// Analysis returns an Analysis playing the book's move for state, or nil if
+// the book does not know the position. A nil Book knows no positions.
+func (book *Book) Analysis(state *GameState) *Analysis {
+	if book == nil {
+		return nil
+	}
+
+	entry, ok := book.Lookup(state)
+	if !ok {
+		return nil
+	}
+
+	candidate := CandidateMove{
+		Move:     entry.Move,
+		Visits:   entry.Visits,
+		WinRate:  entry.WinRate,
+		DrawRate: entry.DrawRate,
+		LossRate: 1 - entry.WinRate - entry.DrawRate,
+		Score:    entry.WinRate + entry.DrawRate/2,
+	}
+	return &Analysis{Candidates: []CandidateMove{candidate}, PrincipalVariation: []Move{entry.Move}, FromBook: true}
+}
+
+// bookPosition is a position BuildBook reaches, with the player whose
+// moves it takes from the book. The other player may make any move.
+type bookPosition struct {
+	state    GameState
+	bookSide int
+}
+
+// BuildBook searches every position with fewer than plies moves made that a
+// player can reach by following the book, whatever the opponent plays, with
+// analyst, spread over all CPUs. Symmetric positions are only searched once.
+func BuildBook(analyst Analyst, plies int) *Book {
+	book := NewBook()
+	frontier := []bookPosition{{*NewGame(), 1}, {*NewGame(), 2}}
+
+	for ply := 0; ply < plies && len(frontier) > 0; ply++ {
+		// Search the positions where the book side is to move...
+		var toSearch []GameState
+		queued := make(map[GameState]bool)
+		for _, position := range frontier {
+			key, _ := Canonical(&position.state)
+			if position.state.Player == position.bookSide && !queued[key] {
+				queued[key] = true
+				toSearch = append(toSearch, position.state)
+			}
+		}
+		book.search(analyst, toSearch)
+		slog.Info("Searched the book positions", "ply", ply, "positions", len(toSearch))
+
+		// ...and follow the book's moves, and every move of the other side.
+		var next []bookPosition
+		seen := make(map[bookPosition]bool)
+		for _, position := range frontier {
+			var moves []*Move
+			if position.state.Player == position.bookSide {
+				entry, ok := book.Lookup(&position.state)
+				if !ok {
+					// The search found no move, so the book ends here.
+					continue
+				}
+				moves = []*Move{&entry.Move}
+			} else {
+				moves = position.state.ValidMoves()
+			}
+
+			for _, move := range moves {
+				child := position.state
+				child.Play(move)
+				key, _ := Canonical(&child)
+				if child.IsOver() || seen[bookPosition{key, position.bookSide}] {
+					continue
+				}
+
+				seen[bookPosition{key, position.bookSide}] = true
+				next = append(next, bookPosition{child, position.bookSide})
+			}
+		}
+		frontier = next
+	}
+
+	return book
+}
+
+// search adds an entry for each of states to the book, searching them with analyst.
+func (book *Book) search(analyst Analyst, states []GameState) {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	work := make(chan *GameState)
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for state := range work {
+				analysis := analyst(state)
+				if len(analysis.Candidates) == 0 {
+					continue
+				}
+				best := analysis.Candidates[0]
+
+				lock.Lock()
+				book.Add(state, BookEntry{Move: best.Move, Visits: best.Visits, WinRate: best.WinRate, DrawRate: best.DrawRate})
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for i := range states {
+		work <- &states[i]
+	}
+	close(work)
+	wg.Wait()
+}
+
+// movesMade returns the number of squares taken on the board.
+func movesMade(state *GameState) int {
+	return 81 - len(state.Board.AllPossibleMoves())
+}
+
+// sortedPositions returns the book's positions, with the fewest moves made
+// first and otherwise ordered by their notation.
+func (book *Book) sortedPositions() []GameState {
+	positions := make([]GameState, 0, len(book.entries))
+	for state := range book.entries {
+		positions = append(positions, state)
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if moves, otherMoves := movesMade(&positions[i]), movesMade(&positions[j]); moves != otherMoves {
+			return moves < otherMoves
+		}
+		return FormatPosition(&positions[i]) < FormatPosition(&positions[j])
+	})
+
+	return positions
+}
+
+// Save writes the book to w: a "version 2" line, a "rules" line naming the
+// rules of its positions as ParseRules reads them, then one line for every
+// position, with the position in the notation of FormatPosition, the move
+// ("boardX boardY tileX tileY"), the win and draw rates and the visits.
+// Lines starting with a '#' are comments.
+func (book *Book) Save(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintln(buffer, "# Ultimate Tic-Tac-Toe opening book")
+	fmt.Fprintf(buffer, "version %d\n", BOOK_VERSION)
+
+	positions := book.sortedPositions()
+	rules := DefaultRules
+	if len(positions) > 0 {
+		rules = positions[0].Rules
+	}
+	fmt.Fprintf(buffer, "rules %s\n", rules)
+
+	for _, state := range positions {
+		entry := book.entries[state]
+		m := entry.Move
+		fmt.Fprintf(buffer, "%s %d %d %d %d %.4f %.4f %d\n", FormatPosition(&state), m.BoardX, m.BoardY, m.TileX, m.TileY, entry.WinRate, entry.DrawRate, entry.Visits)
+	}
+
+	return buffer.Flush()
+}
+
+// ReadBook reads a book written by Save, under the same rules.
+func ReadBook(r io.Reader) (*Book, error) {
+	book := NewBook()
+	sawVersion, sawRules := false, false
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !sawVersion {
+			if len(fields) != 2 || fields[0] != "version" || fields[1] != strconv.Itoa(BOOK_VERSION) {
+				return nil, fmt.Errorf("expected a version %d opening book, got %q", BOOK_VERSION, line)
+			}
+			sawVersion = true
+			continue
+		}
+
+		if !sawRules {
+			if err := readRulesLine(fields); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			sawRules = true
+			continue
+		}
+
+		if len(fields) != 11 {
+			return nil, fmt.Errorf("line %d: expected a position, a move and its statistics, got %q", lineNumber, line)
+		}
+
+		state, err := ParsePosition(strings.Join(fields[:4], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		var entry BookEntry
+		var numbers [7]float64
+		for i, field := range fields[4:] {
+			if numbers[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+		}
+		entry.Move = Move{int(numbers[0]), int(numbers[1]), int(numbers[2]), int(numbers[3])}
+		entry.WinRate, entry.DrawRate, entry.Visits = numbers[4], numbers[5], int(numbers[6])
+
+		if !isValidMove(state, &entry.Move) {
+			return nil, fmt.Errorf("line %d: %v is not a legal move", lineNumber, entry.Move)
+		}
+		book.Add(state, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !sawRules {
+		return nil, fmt.Errorf("no opening book found")
+	}
+
+	return book, nil
+}
+
+// isValidMove returns true if move is one of the moves that can be made in state.
+func isValidMove(state *GameState, move *Move) bool {
+	for _, valid := range state.ValidMoves() {
+		if *valid == *move {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadBook reads the opening book file at path.
+func LoadBook(path string) (*Book, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBook(file)
+}
+
+// String formats the book for inspecting it on the command line: the number
+// of positions with each number of moves made, then every position in the
+// notation of FormatPosition with the book's move.
+func (book *Book) String() string {
+	var buffer bytes.Buffer
+	positions := book.sortedPositions()
+
+	counts := make(map[int]int)
+	for i := range positions {
+		counts[movesMade(&positions[i])] += 1
+	}
+	fmt.Fprintf(&buffer, "%d positions", len(positions))
+	for moves := 0; counts[moves] > 0; moves++ {
+		fmt.Fprintf(&buffer, ", %d after %d moves", counts[moves], moves)
+	}
+	buffer.WriteString("\n")
+
+	for _, state := range positions {
+		entry := book.entries[state]
+		m := entry.Move
+		fmt.Fprintf(&buffer, "\n%s\nplay %d %d %d %d: %.1f%% win, %.1f%% draw, %d visits\n", FormatPosition(&state), m.BoardX, m.BoardY, m.TileX, m.TileY, entry.WinRate*100, entry.DrawRate*100, entry.Visits)
+	}
+
+	return buffer.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBookSymmetries(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 0, 1})
+
+	book := NewBook()
+	book.Add(state, BookEntry{Move: Move{0, 1, 1, 0}, Visits: 100, WinRate: 0.5})
+
+	for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+		transformed := symmetry.State(state)
+		entry, ok := book.Lookup(&transformed)
+		if !ok {
+			t.Fatal("The book does not know the position under symmetry", symmetry)
+		}
+
+		if want := symmetry.Move(Move{0, 1, 1, 0}); entry.Move != want {
+			t.Error("Under symmetry", symmetry, "the book plays", entry.Move, "instead of", want)
+		}
+		if !isValidMove(&transformed, &entry.Move) {
+			t.Error("Under symmetry", symmetry, "the book plays the illegal move", entry.Move)
+		}
+	}
+
+	if book.Len() != 1 {
+		t.Error("Expected a single position in the book, got", book.Len())
+	}
+
+	if _, ok := book.Lookup(NewGame()); ok {
+		t.Error("The book knows a position that was never added")
+	}
+}
+
+func TestBuildBook(t *testing.T) {
+	book := BuildBook(Analysts["random"](DefaultSearchConfig), 3)
+
+	counts := make(map[int]int)
+	for _, state := range book.sortedPositions() {
+		moves := movesMade(&state)
+		counts[moves] += 1
+
+		entry, _ := book.Lookup(&state)
+		if !isValidMove(&state, &entry.Move) {
+			t.Error("The book plays the illegal move", entry.Move, "in", FormatPosition(&state))
+		}
+		if moves == 2 && state.Player != 1 {
+			t.Error("The book has a move for O after two moves in", FormatPosition(&state))
+		}
+	}
+
+	// One opening move, a reply to each of the 15 different first moves,
+	// and X's second move after every reply to its book move, which is
+	// sent to one of the boards.
+	if counts[0] != 1 || counts[1] != 15 || counts[2] < 1 || counts[2] > 9 || len(counts) != 3 {
+		t.Error("Unexpected numbers of positions after each number of moves:", counts)
+	}
+
+	// Whatever X opens with, the book has a reply.
+	for _, move := range NewGame().ValidMoves() {
+		state := NewGame()
+		state.Play(move)
+		if _, ok := book.Lookup(state); !ok {
+			t.Error("The book has no reply to", *move)
+		}
+	}
+}
+
+func TestBuildBookWithoutCandidates(t *testing.T) {
+	noMoves := func(state *GameState) *Analysis { return &Analysis{} }
+	if book := BuildBook(noMoves, 2); book.Len() != 0 {
+		t.Error("Expected an empty book from an analyst without moves, got", book.Len(), "positions")
+	}
+
+	// Without a move for X, the book does not follow X any further.
+	var lock sync.Mutex
+	searchedForX := 0
+	noMovesForX := func(state *GameState) *Analysis {
+		if state.Player == 1 {
+			lock.Lock()
+			searchedForX += 1
+			lock.Unlock()
+			return &Analysis{}
+		}
+		return moveAnalysis(state.ValidMoves()[0])
+	}
+	if book := BuildBook(noMovesForX, 3); book.Len() == 0 || searchedForX != 1 {
+		t.Error("Expected only the first position searched for X, got", searchedForX, "and", book.Len(), "positions")
+	}
+}
+
+func TestBookSaveAndRead(t *testing.T) {
+	book := BuildBook(Analysts["random"](DefaultSearchConfig), 2)
+
+	var buffer bytes.Buffer
+	if err := book.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadBook(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Len() != book.Len() {
+		t.Fatal("Saved", book.Len(), "positions, read", read.Len())
+	}
+	for state, entry := range book.entries {
+		if read.entries[state] != entry {
+			t.Error("Saved", entry, "for", FormatPosition(&state), "but read", read.entries[state])
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		"version 2\n",
+		"version 3\n",
+		"version 1\nX -1 -1 ---------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1 1 0.5 0 10\n",
+		"version 2\nX -1 -1 ---------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1 1 0.5 0 10\n",
+		"version 2\nrules misere\nX -1 -1 ---------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1 1 0.5 0 10\n",
+		"version 2\nrules standard\nX -1 -1 ---------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1\n",
+		"version 2\nrules standard\nX -1 -1 ---------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1 3 0.5 0 10\n",
+		"version 2\nrules standard\nO 0 1 X--------/---------/---------/---------/---------/---------/---------/---------/--------- 1 1 1 1 0.5 0 10\n",
+	} {
+		if _, err := ReadBook(strings.NewReader(input)); err == nil {
+			t.Errorf("Read a book from %q", input)
+		}
+	}
+}
+
+func TestSearchPlaysFromBook(t *testing.T) {
+	book := NewBook()
+	book.Add(NewGame(), BookEntry{Move: Move{1, 1, 0, 2}, Visits: 1000, WinRate: 0.6, DrawRate: 0.2})
+
+	config := DefaultSearchConfig
+	config.Book = book
+	config.MaxPlayouts = 100
+	config.ThinkTime = 0
+	config.MaxDepth = 2
+
+	analysts := map[string]Analyst{
+		"tree search": treeSearchAnalyst(config),
+		"alpha-beta":  Analysts["alphabeta"](config),
+		"PUCT":        Analysts["puct"](config),
+	}
+	for name, analyst := range analysts {
+		analysis := analyst(NewGame())
+		if !analysis.FromBook || *analysis.BestMove() != (Move{1, 1, 0, 2}) {
+			t.Error("The", name, "did not play the book move, got", analysis)
+		}
+
+		state := NewGame()
+		state.Play(&Move{1, 1, 0, 2})
+		if analysis := analyst(state); analysis.FromBook {
+			t.Error("The", name, "played from the book in a position it does not know")
+		}
+	}
+
+	engine := NewEngine(config)
+	if move := engine.Think(NewGame()); *move != (Move{1, 1, 0, 2}) {
+		t.Error("The engine did not play the book move, got", *move)
+	}
+}
@@ -1,151 +1,128 @@
 package main
 
 import (
-	"math/rand"
+	"log/slog"
 	"time"
 )
 
 // RandomBot will make a random move in an empty tile on the
 // board it is forced to, based on the previously made move.
 func RandomBot(previousMove *Move, board *UltimateBoard) *Move {
-	x := previousMove.TileX
-	y := previousMove.TileY
-
-	if (x == -1 && y == -1) || board[previousMove.TileX][previousMove.TileY].HasWinner() != EMPTY || len(board[previousMove.TileX][previousMove.TileY].ValidMoves(previousMove.TileX, previousMove.TileY)) == 0 {
-		// If:
-		//  - This player is starting the game
-		//  - The board the bot is supposed to play on is already won
-		//  - The board the bot is supposed to play on is already full
-		//      -> pick a random tile on a random board instead.
-		validMoves := board.ValidMoves()
-		randomMoveIndex := rand.Intn(len(validMoves))
-		return validMoves[randomMoveIndex]
-	}
-
-	// Else, we need to play on the board corresponding to the last move's Tile X/Y position.
-	validMoves := board[previousMove.TileX][previousMove.TileY].ValidMoves(previousMove.TileX, previousMove.TileY)
-	randomMoveIndex := rand.Intn(len(validMoves))
-	return validMoves[randomMoveIndex]
+	// If this player is starting the game, or the board the bot is sent
+	// to is already won or full, this picks a random tile on a random board.
+	// Which player is to move does not change where they may play.
+	move := squareMove(RandomGameBot(NewGameState(1, previousMove, board)), 3)
+	return &move
 }
 
 // MonteCarloBot uses a Monte Carlo Search Tree to look for the best possible move.
 // The function will use TIME_TO_THINK (globally defined) seconds to try to decide
-// the "best" next move it can make. A win counts as +1, a tie as 0 and loses as -1,
-// each divided with the number of moves it took to reach that state. This means moves
-// where few following moves lead to a win are strongly favored, while moves that within
-// a few moves will lead to a loss are strongly disfavored.
+// the "best" next move it can make. By default (SELECT_LEGACY) a win counts as +1,
+// a tie as 0 and loses as -DefaultSearchConfig.LegacyLossWeight, each divided with
+// the number of moves it took to reach that state. This means moves where few following
+// moves lead to a win are strongly favored, while moves that within a few moves will lead
+// to a loss are strongly disfavored. DefaultSearchConfig.Selection picks another strategy.
 func MonteCarloBot(playerNumber int, previousMove *Move, board *UltimateBoard) *Move {
+	return MonteCarloAnalysis(NewGameState(playerNumber, previousMove, board), DefaultSearchConfig).BestMove()
+}
+
+// MonteCarloAnalysis runs the same search as MonteCarloBot with config, but
+// returns the statistics it gathered for every move instead of just the best
+// one. The search stops once config.ThinkTime or config.MaxPlayouts is used
+// up, and the moves are ranked by config.Selection (SELECT_LEGACY by
+// default). The moves of config.Book are played without searching, and once
+// few enough empty squares are left, the endgame solver takes over instead.
+func MonteCarloAnalysis(state *GameState, config SearchConfig) *Analysis {
 	start := time.Now()
-	var movesToTry []*Move
-
-	x := previousMove.TileX
-	y := previousMove.TileY
-
-	if (x == -1 && y == -1) || board[x][y].HasWinner() != EMPTY || len(board[x][y].ValidMoves(x, y)) == 0 {
-		// If:
-		//  - The player is making the first move
-		//  - The board the bot is supposed to play on is already won
-		//  - The board is already full
-		//      -> The bot can play on any board.
-		movesToTry = board.ValidMoves()
-
-		if len(movesToTry) == 0 {
-			// HackerRank does not properly detect when a game is already
-			// tied, but will force players to fill up all the boards
-			// before calling it, so we keep playing...
-			movesToTry = board.AllPossibleMoves()
-		}
-	} else {
-		// Otherwise we settle for the moves we can
-		// make on the board the bot was "forced to".
-		movesToTry = board[x][y].ValidMoves(x, y)
+	if config.ThinkTime <= 0 && config.MaxPlayouts == 0 {
+		// Nothing would end the search, so it thinks as long as MonteCarloBot.
+		config.ThinkTime = time.Duration(TIME_TO_THINK * float64(time.Second))
+	}
+	if config.Selection == SELECT_DEFAULT {
+		config.Selection = SELECT_LEGACY
+	}
+	if analysis := config.Book.Analysis(state); analysis != nil {
+		slog.Info("MonteCarloBot played from the opening book", "search", analysis)
+		return analysis
 	}
 
-	ties := make(map[Move]float64)
-	wins := make(map[Move]float64)
-	weightedWins := make(map[Move]float64)
-	losses := make(map[Move]float64)
-	weightedLosses := make(map[Move]float64)
-	gamesPlayed := 0
+	if analysis := solveEndgame(state, config.SolverThreshold, config.deadline(start)); analysis != nil {
+		slog.Info("MonteCarloBot solved the position", "search", analysis)
+		return analysis
+	}
 
-	allValidMoves := make([]Move, len(movesToTry))
-	for i := 0; i < len(movesToTry); i++ {
-		allValidMoves[i] = *movesToTry[i]
+	movesToTry := state.ValidMoves()
+	if len(movesToTry) == 0 {
+		// HackerRank does not properly detect when a game is already
+		// tied, but will force players to fill up all the boards
+		// before calling it, so we keep playing...
+		movesToTry = state.Board.AllPossibleMoves()
+	}
+	if len(movesToTry) == 0 {
+		// Every tile is filled, so there is nothing to search.
+		return &Analysis{TimeUsed: time.Since(start)}
 	}
 
-	for {
-		// Until we run out of time...
-		for _, move := range movesToTry {
-			gamesPlayed += 1
+	squares := make([]int, len(movesToTry))
+	for i, move := range movesToTry {
+		squares[i] = moveSquare(*move, 3)
+	}
+	stats, gamesPlayed := monteCarloSearch(state, squares, config.ThinkTime, config.MaxPlayouts, start)
 
-			// Keep track of whose turn it is
-			var previousPlayer int
-			// Keep track of how many moves were needed to end the game
-			var movesUntilGameEnded float64
+	analysis := &Analysis{Playouts: gamesPlayed, TimeUsed: time.Since(start)}
+	analysis.Candidates = rankCandidates(movesToTry, stats, &config)
+	if len(analysis.Candidates) > 0 {
+		// The bot does not look beyond its own move.
+		analysis.PrincipalVariation = []Move{analysis.Candidates[0].Move}
+	}
 
-			// Create a copy of the board
-			localBoard := board.Copy()
-			localMove := move.Copy()
+	slog.Debug("MonteCarloBot ran out of time", "validMoves", len(movesToTry), "gamesPerMove", gamesPlayed/len(movesToTry))
+	slog.Info("MonteCarloBot made its move", "search", analysis)
+	return analysis
+}
+
+// monteCarloSearch plays each of squares on game in turn, simulating the rest
+// of the game at random after each, until thinkTime has passed since start or
+// maxPlayouts games have been played (0 means no limit, for either one). It
+// returns the statistics of every square and the number of games played. A
+// win counts as +1, weighted by the number of moves it took, a tie as 0 and
+// losses are weighted the same way, for SELECT_LEGACY to rank the squares.
+func monteCarloSearch(game Game, squares []int, thinkTime time.Duration, maxPlayouts int, start time.Time) ([]moveStats, int) {
+	stats := make([]moveStats, len(squares))
+	playerMark := PlayerMark(game.ToMove())
+	gamesPlayed := 0
+
+	for maxPlayouts == 0 || gamesPlayed < maxPlayouts {
+		// Until we run out of time...
+		for i, square := range squares {
+			gamesPlayed += 1
 
 			// It's the bot's turn, so the previous player must have made
-			// the last move -> Make the move on the board.
-			if playerNumber == 1 {
-				localBoard[localMove.BoardX][localMove.BoardY][localMove.TileX][localMove.TileY] = PLAYER_1_CONTROLLED
-				previousPlayer = 1
+			// the last move -> Make the move on a copy of the board.
+			localGame := game.Clone()
+			localGame.PlaySquare(square)
+
+			// Simulate the rest of the game with random moves, keeping track
+			// of how many moves were needed to end the game.
+			localBoardWinner, moves := simulateGame(localGame)
+			movesUntilGameEnded := 1.0 + moves
+			stats[i].visits += 1.0
+
+			if localBoardWinner == EMPTY {
+				stats[i].draws += 1.0
+			} else if localBoardWinner == playerMark {
+				stats[i].wins += 1.0
+				stats[i].weightedWins += (1.0 / movesUntilGameEnded)
 			} else {
-				localBoard[localMove.BoardX][localMove.BoardY][localMove.TileX][localMove.TileY] = PLAYER_2_CONTROLLED
-				previousPlayer = 2
-			}
-			movesUntilGameEnded += 1.0
-
-			// Simulate the rest of the game with two RandomBots.
-			for localBoard.HasWinner() == EMPTY {
-				if len(localBoard.ValidMoves()) == 0 {
-					ties[*move] += 1.0
-					break
-				}
-
-				movesUntilGameEnded += 1.0
-
-				localMove = RandomBot(localMove, localBoard)
-				if previousPlayer == 1 {
-					localBoard[localMove.BoardX][localMove.BoardY][localMove.TileX][localMove.TileY] = PLAYER_2_CONTROLLED
-					previousPlayer = 2
-				} else if previousPlayer == 2 {
-					localBoard[localMove.BoardX][localMove.BoardY][localMove.TileX][localMove.TileY] = PLAYER_1_CONTROLLED
-					previousPlayer = 1
-				}
-			}
-
-			localBoardWinner := localBoard.HasWinner()
-			if (playerNumber == 1 && localBoardWinner == PLAYER_1_CONTROLLED) || (playerNumber == 2 && localBoardWinner == PLAYER_2_CONTROLLED) {
-				wins[*move] += 1.0
-				weightedWins[*move] += (1.0 / movesUntilGameEnded)
-			} else if (playerNumber == 1 && localBoardWinner == PLAYER_2_CONTROLLED) || (playerNumber == 2 && localBoardWinner == PLAYER_1_CONTROLLED) {
-				losses[*move] += 1.0
-				weightedLosses[*move] += (1.0 / movesUntilGameEnded)
+				stats[i].weightedLosses += (1.0 / movesUntilGameEnded)
 			}
 		}
 
 		// Break when the bot runs out of time
-		end := time.Now()
-		if end.Sub(start).Seconds() > TIME_TO_THINK {
-			//fmt.Printf("MonteCarloBot had time to play %d simulated games using %d valid moves (~%d per valid move) before running out of time!\n", gamesPlayed, len(movesToTry), (gamesPlayed / len(movesToTry)))
+		if thinkTime > 0 && time.Since(start) > thinkTime {
 			break
 		}
 	}
 
-	bestScore := -10000.0
-	var bestMove Move
-
-	for _, move := range allValidMoves {
-		score := (weightedWins[move] - (weightedLosses[move] * 2.0)) / (wins[move] + losses[move] + ties[move])
-		if score > bestScore {
-			bestScore = score
-			bestMove = move
-		}
-	}
-
-	// fmt.Printf("The best move (%s) had a score of %f\n", bestMoveString, bestScore)
-	return &bestMove
+	return stats, gamesPlayed
 }
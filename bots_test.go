@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestRandomBot(t *testing.T) {
 	var board UltimateBoard
@@ -41,3 +44,39 @@ func TestMonteCarloBot(t *testing.T) {
 		t.Error("Instead of (1,1), it played on board (", smartMove.BoardX, ",", smartMove.BoardY, ")")
 	}
 }
+
+func TestMonteCarloBotFullBoard(t *testing.T) {
+	var board UltimateBoard
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				for l := 0; l < 3; l++ {
+					board[i][j][k][l] = PLAYER_1_CONTROLLED + (i+j+k+l)%2*(PLAYER_2_CONTROLLED-PLAYER_1_CONTROLLED)
+				}
+			}
+		}
+	}
+
+	if move := MonteCarloBot(1, &Move{0, 0, 0, 0}, &board); move != nil {
+		t.Error("MonteCarloBot played", *move, "on a full board")
+	}
+}
+
+func TestMonteCarloAnalysisConfig(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	// Nine moves on board (1,1), five playouts each.
+	config := DefaultSearchConfig
+	config.ThinkTime = time.Hour
+	config.MaxPlayouts = 45
+	if analysis := Analysts["montecarlo"](config)(state); analysis.Playouts != 45 || len(analysis.Candidates) != 9 {
+		t.Error("Expected 45 playouts over 9 moves, got", analysis.Playouts, "over", len(analysis.Candidates))
+	}
+
+	config.Book = NewBook()
+	config.Book.Add(state, BookEntry{Move: Move{1, 1, 2, 2}, Visits: 1})
+	if analysis := Analysts["montecarlo"](config)(state); !analysis.FromBook || *analysis.BestMove() != (Move{1, 1, 2, 2}) {
+		t.Error("Expected the move from the book, got", analysis.BestMove())
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Engine keeps a SearchTree alive between moves, which lets it keep
+// thinking while the opponent decides on their move ("pondering").
+// Once the opponent's reply arrives, the part of the tree below that
+// reply is reused instead of starting the search over from scratch.
+type Engine struct {
+	config SearchConfig
+	tree   *SearchTree
+
+	// Pondering is running while stop is non-nil. Closing stop
+	// ends it, after which the final playout count arrives on done.
+	stop chan struct{}
+	done chan int
+}
+
+// NewEngine returns an Engine searching with config.
+func NewEngine(config SearchConfig) *Engine {
+	return &Engine{config: config}
+}
+
+// Think stops any pondering in progress, searches the position in state
+// and returns the best move found. The move is played on the engine's
+// tree, so that a following call to Ponder searches the opponent's replies.
+func (engine *Engine) Think(state *GameState) *Move {
+	if playouts := engine.StopPondering(); playouts > 0 {
+		slog.Debug("Engine stopped pondering", "playouts", playouts)
+	}
+
+	start := time.Now()
+	reused := engine.sync(state)
+	if analysis := engine.config.Book.Analysis(state); analysis != nil {
+		slog.Info("Engine played from the opening book", "search", analysis)
+		move := analysis.BestMove()
+		engine.tree.Advance(move)
+		return move
+	}
+
+	if analysis := solveEndgame(state, engine.config.SolverThreshold, engine.config.deadline(start)); analysis != nil {
+		slog.Info("Engine solved the position", "search", analysis)
+		move := analysis.BestMove()
+		engine.tree.Advance(move)
+		return move
+	}
+
+	// The time the solver took is taken off the search's.
+	engine.tree.config = engine.config.remaining(start)
+	engine.tree.Search(nil)
+
+	slog.Info("Engine made its move", "reusedTree", reused, "search", treeAnalysis{engine.tree})
+	move := engine.tree.BestMove()
+	if move != nil {
+		engine.tree.Advance(move)
+	}
+
+	return move
+}
+
+// sync makes sure the root of the engine's tree is the position in state,
+// reusing the tree if state is reached by one of its root's moves. It
+// returns true if the tree was reused.
+func (engine *Engine) sync(state *GameState) bool {
+	if engine.tree != nil {
+		if engine.tree.state == *state {
+			return true
+		}
+
+		for _, child := range engine.tree.root.children {
+			next := engine.tree.state
+			next.Play(&child.move)
+			if next == *state {
+				engine.tree.Advance(&child.move)
+				return true
+			}
+		}
+	}
+
+	engine.tree = NewSearchTree(state, engine.config)
+	return false
+}
+
+// Ponder starts searching the position after the engine's last move in
+// the background, until StopPondering is called. It does nothing if the
+// engine has not made a move yet, or is already pondering.
+func (engine *Engine) Ponder() {
+	if engine.tree == nil || engine.stop != nil || engine.tree.state.IsOver() {
+		return
+	}
+
+	// Pondering lasts for as long as the opponent thinks.
+	engine.tree.config.ThinkTime = 0
+	engine.tree.config.MaxPlayouts = 0
+
+	engine.stop = make(chan struct{})
+	engine.done = make(chan int, 1)
+	go func(tree *SearchTree, stop <-chan struct{}, done chan<- int) {
+		done <- tree.Search(stop)
+	}(engine.tree, engine.stop, engine.done)
+}
+
+// StopPondering stops the background search started by Ponder and waits
+// for it to finish. It returns the number of playouts made while pondering.
+func (engine *Engine) StopPondering() int {
+	if engine.stop == nil {
+		return 0
+	}
+
+	close(engine.stop)
+	playouts := <-engine.done
+	engine.stop = nil
+	engine.done = nil
+
+	return playouts
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnginePonder(t *testing.T) {
+	engine := NewEngine(testSearchConfig)
+	state := NewGame()
+
+	move := engine.Think(state)
+	state.Play(move)
+
+	engine.Ponder()
+	time.Sleep(50 * time.Millisecond)
+	if engine.StopPondering() == 0 {
+		t.Error("The engine did not make any playouts while pondering!")
+	}
+
+	if engine.StopPondering() != 0 {
+		t.Error("Stopping an engine which is not pondering should be a no-op!")
+	}
+
+	// Play the reply the engine looked at the most, and check that the engine
+	// picks up the tree it grew while pondering instead of starting over.
+	reply := engine.tree.BestMove()
+	state.Play(reply)
+	engine.sync(state)
+	if engine.tree.state != *state || engine.tree.root.visits == 0 {
+		t.Error("The engine did not reuse the tree it built while pondering!")
+	}
+}
+
+func TestRunPersistentIllegalMoves(t *testing.T) {
+	for _, input := range []string{
+		"O\n9 9 9 9\n",   // Off the board
+		"O\n1 1 one 1\n", // Not a move
+		"X\n1 1 1 1\n",   // Not the opponent's turn
+	} {
+		var out bytes.Buffer
+		if err := runPersistent(strings.NewReader(input), &out); err == nil || out.Len() != 0 {
+			t.Errorf("Expected %q to be rejected without a move, got %v and %q", input, err, out.String())
+		}
+	}
+}
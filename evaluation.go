@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The features of a position the Evaluator weighs, each counted for the
+// player to move minus the same count for the opponent.
+const (
+	FEATURE_BIAS         = iota // Always 1, the value of having the move
+	FEATURE_BOARDS              // Boards won
+	FEATURE_MICRO_TWOS          // Lines of two with the third tile empty, on open boards
+	FEATURE_MACRO_TWOS          // Lines of two won boards with the third board open
+	FEATURE_FREE_MOVE           // 1 if the player to move may play on any board
+	FEATURE_CENTER_BOARD        // Owning the center board
+	FEATURE_CENTER_TILES        // Center tiles of open boards
+	NUM_FEATURES
+)
+
+// featureNames are the names of the features in a weights file.
+var featureNames = [NUM_FEATURES]string{"bias", "boards", "micro-twos", "macro-twos", "free-move", "center-board", "center-tiles"}
+
+// WEIGHTS_VERSION is the version of the weights file format written by Save.
+const WEIGHTS_VERSION = 1
+
+// Features holds the feature values of a position, indexed by FEATURE_*.
+type Features [NUM_FEATURES]float64
+
+// winningLines are the lines of three on a 3x3 board.
+var winningLines = [8][3][2]int{
+	{{0, 0}, {0, 1}, {0, 2}}, {{1, 0}, {1, 1}, {1, 2}}, {{2, 0}, {2, 1}, {2, 2}},
+	{{0, 0}, {1, 0}, {2, 0}}, {{0, 1}, {1, 1}, {2, 1}}, {{0, 2}, {1, 2}, {2, 2}},
+	{{0, 0}, {1, 1}, {2, 2}}, {{2, 0}, {1, 1}, {0, 2}},
+}
+
+// DefaultEvaluator holds weights learned by TrainEvaluator from 20000
+// self-play games of the "policy" bot, used unless a weights file is given.
+var DefaultEvaluator = &Evaluator{Weights: Features{0.151, 0.427, 1.241, 1.110, 0.026, 0.091, -0.060}}
+
+// Evaluator estimates the result of a position from a weighted sum of its
+// features, with weights learned by logistic regression on self-play games.
+type Evaluator struct {
+	Weights Features
+}
+
+// Evaluate returns the expected result of state for the player to move, from
+// 0 (a certain loss) to 1 (a certain win), counting a tie as half a win.
+func (evaluator *Evaluator) Evaluate(state *GameState) float64 {
+	features := evaluationFeatures(state)
+	return evaluator.predict(&features)
+}
+
+// predict returns the expected result of a position with features.
+func (evaluator *Evaluator) predict(features *Features) float64 {
+	var sum float64
+	for i, weight := range evaluator.Weights {
+		sum += weight * features[i]
+	}
+
+	return 1 / (1 + math.Exp(-sum))
+}
+
+// evaluationFeatures returns the features of state, from the point of
+// view of the player to move. The counts are scaled to about -1 to 1.
+// The features about won boards are turned around under the misère
+// rules, and lines of boards are left out under the most-boards rules.
+func evaluationFeatures(state *GameState) Features {
+	mark := PlayerMark(state.Player)
+	opponentMark := PlayerMark(Opponent(state.Player))
+
+	// Who has won which board, and which boards can still be played on.
+	var macro TictactoeBoard
+	var open [3][3]bool
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			macro[i][j] = state.Board[i][j].HasWinner()
+			open[i][j] = macro[i][j] == EMPTY && hasEmptyTile(&state.Board[i][j], -1, -1)
+		}
+	}
+
+	// ownership returns 1 for squares owned by the player to
+	// move, -1 for squares owned by the opponent and 0 otherwise.
+	ownership := func(square int) float64 {
+		switch square {
+		case mark:
+			return 1
+		case opponentMark:
+			return -1
+		}
+		return 0
+	}
+
+	var features Features
+	features[FEATURE_BIAS] = 1
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			features[FEATURE_BOARDS] += ownership(macro[i][j]) / 3
+			if open[i][j] {
+				features[FEATURE_MICRO_TWOS] += lineTwos(&state.Board[i][j], mark, opponentMark, nil) / 8
+				features[FEATURE_CENTER_TILES] += ownership(state.Board[i][j][1][1]) / 3
+			}
+		}
+	}
+
+	features[FEATURE_MACRO_TWOS] = lineTwos(&macro, mark, opponentMark, &open) / 2
+	features[FEATURE_CENTER_BOARD] = ownership(macro[1][1])
+	if _, _, forced := state.ForcedBoard(); !forced {
+		features[FEATURE_FREE_MOVE] = 1
+	}
+
+	switch {
+	case state.Rules&RULES_MISERE != 0:
+		// Boards won are a step towards completing a line of them, which
+		// loses, so the player with fewer of them is the better off.
+		features[FEATURE_BOARDS] = -features[FEATURE_BOARDS]
+		features[FEATURE_MACRO_TWOS] = -features[FEATURE_MACRO_TWOS]
+		features[FEATURE_CENTER_BOARD] = -features[FEATURE_CENTER_BOARD]
+	case state.Rules&RULES_MOST_BOARDS != 0:
+		// Lines of boards do not count for anything.
+		features[FEATURE_MACRO_TWOS] = 0
+	}
+
+	return features
+}
+
+// lineTwos returns the number of lines on board where mark has two squares
+// and the third is empty, minus the same number for opponentMark. If open is
+// not nil, the third square must also be open.
+func lineTwos(board *TictactoeBoard, mark, opponentMark int, open *[3][3]bool) float64 {
+	var twos float64
+	for _, line := range winningLines {
+		var marks, opponentMarks, empty int
+		for _, square := range line {
+			switch board[square[0]][square[1]] {
+			case mark:
+				marks += 1
+			case opponentMark:
+				opponentMarks += 1
+			default:
+				if open == nil || open[square[0]][square[1]] {
+					empty += 1
+				}
+			}
+		}
+
+		if empty == 1 && marks == 2 {
+			twos += 1
+		} else if empty == 1 && opponentMarks == 2 {
+			twos -= 1
+		}
+	}
+
+	return twos
+}
+
+// Save writes the weights to w, in the format read by ReadEvaluator.
+func (evaluator *Evaluator) Save(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintln(buffer, "# Ultimate Tic-Tac-Toe evaluation weights")
+	fmt.Fprintf(buffer, "version %d\n", WEIGHTS_VERSION)
+	for i, weight := range evaluator.Weights {
+		fmt.Fprintf(buffer, "%s %g\n", featureNames[i], weight)
+	}
+
+	return buffer.Flush()
+}
+
+// ReadEvaluator reads weights written by Save: a "version 1" line followed
+// by a line with the name and weight of every feature. Lines starting with
+// a '#' are comments, and features left out get a weight of 0.
+func ReadEvaluator(r io.Reader) (*Evaluator, error) {
+	evaluator := &Evaluator{}
+	sawVersion := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("expected a name and a value, got %q", line)
+		}
+
+		if !sawVersion {
+			if fields[0] != "version" || fields[1] != strconv.Itoa(WEIGHTS_VERSION) {
+				return nil, fmt.Errorf("expected version %d weights, got %q", WEIGHTS_VERSION, line)
+			}
+			sawVersion = true
+			continue
+		}
+
+		feature := -1
+		for i, name := range featureNames {
+			if name == fields[0] {
+				feature = i
+			}
+		}
+		if feature < 0 {
+			return nil, fmt.Errorf("unknown feature %q", fields[0])
+		}
+
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad weight for %s: %v", fields[0], err)
+		}
+		evaluator.Weights[feature] = weight
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !sawVersion {
+		return nil, fmt.Errorf("no weights found")
+	}
+
+	return evaluator, nil
+}
+
+// LoadEvaluator reads the weights file at path.
+func LoadEvaluator(path string) (*Evaluator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadEvaluator(file)
+}
+
+// RANDOM_OPENING_MOVES is how many moves at the start of every self-play
+// game are made at random, so that the games are not all alike.
+const RANDOM_OPENING_MOVES = 4
+
+// TRAINING_RATE is the learning rate TrainEvaluator is used with.
+const TRAINING_RATE = 0.01
+
+// TrainingPosition is a position from a self-play game, reduced to
+// its features, with the result of the game for the player to move.
+type TrainingPosition struct {
+	Features Features
+	Result   float64 // 1 for a win, 0.5 for a tie and 0 for a loss
+}
+
+// SelfPlayPositions plays games games of bot against itself and returns
+// every position reached after the random opening moves.
+func SelfPlayPositions(bot Bot, games int, random *rand.Rand) []TrainingPosition {
+	var positions []TrainingPosition
+	for game := 0; game < games; game++ {
+		state := NewGame()
+		var gamePositions []TrainingPosition
+		var players []int
+
+		for moves := 0; !state.IsOver(); moves++ {
+			if moves < RANDOM_OPENING_MOVES {
+				validMoves := state.ValidMoves()
+				state.Play(validMoves[random.Intn(len(validMoves))])
+				continue
+			}
+
+			gamePositions = append(gamePositions, TrainingPosition{Features: evaluationFeatures(state)})
+			players = append(players, state.Player)
+			state.Play(bot(state))
+		}
+
+		winner := state.Winner()
+		for i := range gamePositions {
+			switch winner {
+			case EMPTY:
+				gamePositions[i].Result = 0.5
+			case PlayerMark(players[i]):
+				gamePositions[i].Result = 1
+			}
+		}
+
+		positions = append(positions, gamePositions...)
+	}
+
+	return positions
+}
+
+// TrainEvaluator fits the weights of an Evaluator to positions by logistic
+// regression, with epochs passes of stochastic gradient descent.
+func TrainEvaluator(positions []TrainingPosition, epochs int, learningRate float64, random *rand.Rand) *Evaluator {
+	evaluator := &Evaluator{}
+	order := random.Perm(len(positions))
+
+	for epoch := 0; epoch < epochs; epoch++ {
+		random.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+
+		for _, i := range order {
+			position := &positions[i]
+			gradient := evaluator.predict(&position.Features) - position.Result
+			for feature := range evaluator.Weights {
+				evaluator.Weights[feature] -= learningRate * gradient * position.Features[feature]
+			}
+		}
+
+		slog.Debug("Trained the evaluator", "epoch", epoch+1, "loss", evaluator.Loss(positions))
+	}
+
+	return evaluator
+}
+
+// Loss returns the mean cross-entropy between the predicted and actual
+// results of positions, the quantity TrainEvaluator minimises.
+func (evaluator *Evaluator) Loss(positions []TrainingPosition) float64 {
+	var loss float64
+	for i := range positions {
+		predicted := math.Min(math.Max(evaluator.predict(&positions[i].Features), 1e-9), 1-1e-9)
+		result := positions[i].Result
+		loss -= result*math.Log(predicted) + (1-result)*math.Log(1-predicted)
+	}
+
+	return loss / float64(len(positions))
+}
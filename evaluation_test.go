@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEvaluationFeatures(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won board (0,0) and has two in a row on board (1,1).
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[1][1][0][0] = PLAYER_1_CONTROLLED
+	state.Board[1][1][0][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][1][1] = PLAYER_2_CONTROLLED
+	state.LastMove = Move{1, 1, 0, 0}
+
+	features := evaluationFeatures(state)
+	if features[FEATURE_BOARDS] != 1.0/3 || features[FEATURE_MICRO_TWOS] != 1.0/8 || features[FEATURE_CENTER_TILES] != -1.0/3 {
+		t.Error("Unexpected features for player 1:", features)
+	}
+
+	if features[FEATURE_MACRO_TWOS] != 0 || features[FEATURE_FREE_MOVE] != 1 {
+		t.Error("Expected no macro twos and a free move, got", features)
+	}
+
+	// The same position is worth the opposite to the other player.
+	state.Player = 2
+	opponentFeatures := evaluationFeatures(state)
+	for i := FEATURE_BOARDS; i < NUM_FEATURES; i++ {
+		if i != FEATURE_FREE_MOVE && opponentFeatures[i] != -features[i] {
+			t.Error("Expected feature", featureNames[i], "to change sign for player 2, got", opponentFeatures[i], "and", features[i])
+		}
+	}
+
+	// Player 1 wins the center board too, making a line of two boards.
+	state.Player = 1
+	state.Board[1][1][0][2] = PLAYER_1_CONTROLLED
+	features = evaluationFeatures(state)
+	if features[FEATURE_BOARDS] != 2.0/3 || features[FEATURE_MACRO_TWOS] != 0.5 || features[FEATURE_CENTER_BOARD] != 1 {
+		t.Error("Unexpected features with two boards won:", features)
+	}
+
+	state.Rules = RULES_MISERE
+	misere := evaluationFeatures(state)
+	if misere[FEATURE_BOARDS] != -2.0/3 || misere[FEATURE_MACRO_TWOS] != -0.5 || misere[FEATURE_CENTER_BOARD] != -1 {
+		t.Error("Expected the features of won boards to change sign under the misère rules, got", misere)
+	}
+
+	state.Rules = RULES_MOST_BOARDS
+	mostBoards := evaluationFeatures(state)
+	if mostBoards[FEATURE_BOARDS] != 2.0/3 || mostBoards[FEATURE_MACRO_TWOS] != 0 {
+		t.Error("Expected no macro twos under the most-boards rules, got", mostBoards)
+	}
+}
+
+func TestEvaluatorSaveAndRead(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := DefaultEvaluator.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	evaluator, err := ReadEvaluator(&buffer)
+	if err != nil {
+		t.Fatal("Failed to read saved weights:", err)
+	}
+
+	if evaluator.Weights != DefaultEvaluator.Weights {
+		t.Error("Expected the weights", DefaultEvaluator.Weights, "got", evaluator.Weights)
+	}
+}
+
+func TestReadEvaluatorErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"bias 1\n",
+		"version 2\nbias 1\n",
+		"version 1\ncorners 1\n",
+		"version 1\nbias one\n",
+	}
+
+	for _, test := range tests {
+		if _, err := ReadEvaluator(strings.NewReader(test)); err == nil {
+			t.Errorf("Expected an error reading %q", test)
+		}
+	}
+}
+
+func TestTrainEvaluator(t *testing.T) {
+	// The policy bot draws from random too, so that the games are repeatable,
+	// and plays enough of them for the weight of won boards to settle.
+	random := rand.New(rand.NewSource(1))
+	policy := EpsilonGreedyPolicy{Epsilon: DEFAULT_EPSILON, Greedy: HeavyPolicy{random}, Random: random}
+	bot := func(state *GameState) *Move {
+		return policy.ChooseMove(state, state.ValidMoves())
+	}
+	positions := SelfPlayPositions(bot, 1000, random)
+	if len(positions) == 0 {
+		t.Fatal("Expected self-play to produce positions")
+	}
+
+	evaluator := TrainEvaluator(positions, 5, TRAINING_RATE, random)
+	if evaluator.Weights[FEATURE_BOARDS] <= 0 || evaluator.Weights[FEATURE_MACRO_TWOS] <= 0 {
+		t.Error("Expected winning boards and lines of boards to be learned as good, got", evaluator.Weights)
+	}
+
+	if untrained := (&Evaluator{}).Loss(positions); evaluator.Loss(positions) >= untrained {
+		t.Error("Expected training to reduce the loss below", untrained, "got", evaluator.Loss(positions))
+	}
+}
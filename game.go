@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Game is a position of a game played on a board of boards, like
+// GameState, a GeneralBoard or a RecursiveBoard, which the generic bots
+// can play without knowing the shape of the board. A move is the index of
+// the square to play on, as numbered by the game (see moveSquare).
+type Game interface {
+	ToMove() int           // The player (1 or 2) whose turn it is
+	Moves() []int          // The squares the player to move may play on
+	PlaySquare(square int) // Plays on square for the player to move, without checking it is legal
+	Winner() int           // PLAYER_1_CONTROLLED, PLAYER_2_CONTROLLED or EMPTY
+	IsOver() bool          // The game is won, or no moves are left
+	Clone() Game           // A copy which moves can be played on
+}
+
+// A GameBot decides which square to play on in a position of any Game.
+type GameBot func(game Game) int
+
+// GameBots holds the bots that can play any Game, by name, created from
+// the SearchConfig to use like the Analysts.
+var GameBots = map[string]func(config SearchConfig) GameBot{
+	"random": func(config SearchConfig) GameBot {
+		return RandomGameBot
+	},
+	"montecarlo": func(config SearchConfig) GameBot {
+		// Plays like MonteCarloBot, for config.ThinkTime or config.MaxPlayouts.
+		if config.ThinkTime <= 0 && config.MaxPlayouts == 0 {
+			config.ThinkTime = time.Duration(TIME_TO_THINK * float64(time.Second))
+		}
+		if config.Selection == SELECT_DEFAULT {
+			config.Selection = SELECT_LEGACY
+		}
+		return func(game Game) int {
+			squares := game.Moves()
+			stats, _ := monteCarloSearch(game, squares, config.ThinkTime, config.MaxPlayouts, time.Now())
+			_, order := rankMoves(stats, &config)
+			return squares[order[0]]
+		}
+	},
+}
+
+// NewVariant returns the start of a game of the variant called name:
+// "NxN:K" for a GeneralBoard (see ParseVariant), or "levels:D" for a
+// RecursiveBoard with boards nested D levels deep.
+func NewVariant(name string) (Game, error) {
+	if levels, ok := strings.CutPrefix(name, "levels:"); ok {
+		var depth int
+		if _, err := fmt.Sscanf(levels, "%d", &depth); err != nil {
+			return nil, fmt.Errorf("invalid variant %q, expected levels:D like levels:3", name)
+		}
+		return NewRecursiveBoard(depth)
+	}
+
+	return ParseVariant(name)
+}
+
+// moveSquare returns the index of the square move plays on, on a board
+// of size x size boards of size x size squares: the squares of board
+// (0, 0) come first, row by row, then those of board (0, 1) and so on.
+func moveSquare(move Move, size int) int {
+	return ((move.BoardX*size+move.BoardY)*size+move.TileX)*size + move.TileY
+}
+
+// squareMove returns the Move playing on square, the opposite of moveSquare.
+func squareMove(square, size int) Move {
+	return Move{square / (size * size * size), square / (size * size) % size, square / size % size, square % size}
+}
+
+// ToMove returns the player whose turn it is.
+func (state *GameState) ToMove() int {
+	return state.Player
+}
+
+// Moves returns the squares of state.ValidMoves.
+func (state *GameState) Moves() []int {
+	validMoves := state.ValidMoves()
+	squares := make([]int, len(validMoves))
+	for i, move := range validMoves {
+		squares[i] = moveSquare(*move, 3)
+	}
+
+	return squares
+}
+
+// PlaySquare plays the move on square.
+func (state *GameState) PlaySquare(square int) {
+	move := squareMove(square, 3)
+	state.Play(&move)
+}
+
+// Clone returns a copy of state.
+func (state *GameState) Clone() Game {
+	clone := *state
+	return &clone
+}
+
+// RandomGameBot plays on a random square it may play on. RandomBot plays
+// the same way on a GameState.
+func RandomGameBot(game Game) int {
+	moves := game.Moves()
+	return moves[rand.Intn(len(moves))]
+}
+
+// simulateGame plays out the rest of game at random, and returns the
+// winner (EMPTY for a tie) and the number of moves made. A GameState is
+// played out by simulate, which keeps track of the status of its boards.
+func simulateGame(game Game) (int, float64) {
+	if state, ok := game.(*GameState); ok {
+		return simulate(state, nil, nil)
+	}
+
+	var movesMade float64
+	for !game.IsOver() {
+		game.PlaySquare(RandomGameBot(game))
+		movesMade += 1.0
+	}
+
+	return game.Winner(), movesMade
+}
+
+// PlayGameOf plays game out between player1 (X) and player2 (O), returning
+// the winner (PLAYER_1_CONTROLLED, PLAYER_2_CONTROLLED or EMPTY for a tie).
+func PlayGameOf(game Game, player1, player2 GameBot) int {
+	for !game.IsOver() {
+		if game.ToMove() == 1 {
+			game.PlaySquare(player1(game))
+		} else {
+			game.PlaySquare(player2(game))
+		}
+	}
+
+	return game.Winner()
+}
+
+// GamePerft counts the positions reached after exactly depth moves from
+// game, like Perft.
+func GamePerft(game Game, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	if game.IsOver() {
+		return 0
+	}
+
+	moves := game.Moves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, move := range moves {
+		next := game.Clone()
+		next.PlaySquare(move)
+		nodes += GamePerft(next, depth-1)
+	}
+
+	return nodes
+}
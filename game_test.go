@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMoveSquare(t *testing.T) {
+	for _, size := range []int{3, 4} {
+		for square := 0; square < size*size*size*size; square++ {
+			if moveSquare(squareMove(square, size), size) != square {
+				t.Errorf("Square %d of size %d does not round trip: %v", square, size, squareMove(square, size))
+			}
+		}
+	}
+
+	if square := moveSquare(Move{1, 2, 0, 1}, 3); square != 46 {
+		t.Error("Expected the move 1 2 0 1 to play on square 46, got", square)
+	}
+}
+
+func TestGameStatePerft(t *testing.T) {
+	// The Game interface must generate the same moves as GameState does.
+	for _, test := range perftPositions {
+		state, _ := ReadGameState(strings.NewReader(test.position))
+		for depth, expected := range test.counts[:4] {
+			if nodes := GamePerft(state, depth); nodes != expected {
+				t.Errorf("%s: GamePerft(%d) = %d, expected %d", test.name, depth, nodes, expected)
+			}
+		}
+	}
+}
+
+func TestGameBots(t *testing.T) {
+	config := DefaultSearchConfig
+	config.ThinkTime = 10 * time.Millisecond
+	config.MaxPlayouts = 200
+
+	for name, newBot := range GameBots {
+		bot := newBot(config)
+		for _, game := range []Game{NewGame(), mustGeneralBoard(t, 4, 3)} {
+			winner := PlayGameOf(game, func(game Game) int {
+				square := bot(game)
+				if !containsSquare(game.Moves(), square) {
+					t.Fatalf("%s played on square %d, which is not a valid move", name, square)
+				}
+				return square
+			}, RandomGameBot)
+
+			if !game.IsOver() || winner != game.Winner() {
+				t.Errorf("%s: expected the game to be over with winner %c, got %c", name, game.Winner(), winner)
+			}
+		}
+	}
+}
+
+func TestMonteCarloGameBotWins(t *testing.T) {
+	// X has won boards (0,0) and (1,1) and is sent to (2,2), where it wins the game.
+	state, _ := ReadGameState(strings.NewReader("X\n2 2\nX--XOXO--\n-X-XOO---\n--XOXX---\n---XXX-O-\n-O-OO----\n---------\n---O--X--\n-------X-\nO-O------\n"))
+	config := DefaultSearchConfig
+	config.MaxPlayouts = 100
+
+	if square := GameBots["montecarlo"](config)(state); squareMove(square, 3) != (Move{2, 2, 2, 2}) {
+		t.Error("Expected the bot to win the game with 2 2 2 2, got", squareMove(square, 3))
+	}
+}
+
+func TestMonteCarloSearchPlayouts(t *testing.T) {
+	// Without a time limit, the search plays every one of its playouts.
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+	stats, playouts := monteCarloSearch(state, state.Moves(), 0, 90, time.Now())
+
+	var visits float64
+	for i := range stats {
+		visits += stats[i].visits
+	}
+	if playouts != 90 || visits != 90 {
+		t.Error("Expected 90 playouts, got", playouts, "and", visits, "visits")
+	}
+}
+
+// containsSquare returns true if square is one of squares.
+func containsSquare(squares []int, square int) bool {
+	for _, candidate := range squares {
+		if candidate == square {
+			return true
+		}
+	}
+
+	return false
+}
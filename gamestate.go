@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GameState bundles everything needed to continue a game from a given
+// position: the board, the last move made (which decides the board the
+// next player is forced to play on) and whose turn it is.
+type GameState struct {
+	Board    UltimateBoard
+	LastMove Move
+	Player   int   // The player (1 or 2) whose turn it is
+	Rules    Rules // The rule variants the game is played with
+}
+
+// NewGameState returns a GameState for playerNumber to move, using the
+// same (previousMove, board) pair the bots are called with, under the
+// DefaultRules.
+func NewGameState(playerNumber int, previousMove *Move, board *UltimateBoard) *GameState {
+	return &GameState{*board, *previousMove, playerNumber, DefaultRules}
+}
+
+// NewGame returns the GameState at the start of a game under the
+// DefaultRules, with an empty board and player 1 free to play on any board.
+func NewGame() *GameState {
+	state := &GameState{LastMove: Move{-1, -1, -1, -1}, Player: 1, Rules: DefaultRules}
+	state.Board.Clear()
+	return state
+}
+
+// PlayerMark returns the value a square controlled by playerNumber
+// holds (PLAYER_1_CONTROLLED || PLAYER_2_CONTROLLED).
+func PlayerMark(playerNumber int) int {
+	if playerNumber == 1 {
+		return PLAYER_1_CONTROLLED
+	}
+	return PLAYER_2_CONTROLLED
+}
+
+// Opponent returns the number of the player playing against playerNumber.
+func Opponent(playerNumber int) int {
+	return 3 - playerNumber
+}
+
+// ForcedBoard returns the board the player to move has to play on, and
+// false if the player is free to play on any board.
+func (state *GameState) ForcedBoard() (int, int, bool) {
+	if state.Rules&RULES_FREE_MOVES != 0 {
+		return -1, -1, false
+	}
+
+	return forcedBoard(&state.Board, &state.LastMove)
+}
+
+// ValidMoves returns a slice of *Move, containing all the moves the
+// player to move is allowed to make, following the forced board rule
+// unless the Rules drop it.
+func (state *GameState) ValidMoves() []*Move {
+	if state.Rules&RULES_FREE_MOVES != 0 {
+		return state.Board.ValidMoves()
+	}
+
+	return validMoves(&state.Board, &state.LastMove)
+}
+
+// forcedBoard returns the board a player has to play on after previousMove,
+// and false if the player is free to play on any board.
+func forcedBoard(board *UltimateBoard, previousMove *Move) (int, int, bool) {
+	x := previousMove.TileX
+	y := previousMove.TileY
+
+	if (x == -1 && y == -1) || board[x][y].HasWinner() != EMPTY || !hasEmptyTile(&board[x][y], -1, -1) {
+		// If:
+		//  - The player is making the first move
+		//  - The board the player is sent to is already won
+		//  - The board the player is sent to is already full
+		//      -> The player can play on any board.
+		return -1, -1, false
+	}
+
+	return x, y, true
+}
+
+// validMoves returns the moves a player is allowed to make after
+// previousMove, following the forced board rule.
+func validMoves(board *UltimateBoard, previousMove *Move) []*Move {
+	if x, y, forced := forcedBoard(board, previousMove); forced {
+		// forcedBoard has already checked that the board is not won.
+		return board[x][y].emptyTiles(x, y)
+	}
+
+	return board.ValidMoves()
+}
+
+// Play makes move on the board for the player to move and passes
+// the turn to the other player. It does not check that the move is legal.
+func (state *GameState) Play(move *Move) {
+	state.Board[move.BoardX][move.BoardY][move.TileX][move.TileY] = PlayerMark(state.Player)
+	state.LastMove = *move
+	state.Player = Opponent(state.Player)
+}
+
+// Winner returns the player who has won the game under its Rules
+// (PLAYER_1_CONTROLLED or PLAYER_2_CONTROLLED), or EMPTY.
+func (state *GameState) Winner() int {
+	return state.Rules.Winner(&state.Board)
+}
+
+// IsOver returns true if either player has won the game, or if
+// there are no valid moves left (a tie).
+func (state *GameState) IsOver() bool {
+	return state.Winner() != EMPTY || len(state.Board.ValidMoves()) == 0
+}
+
+// ReadGameState reads a board state in HackerRank's format: a line saying
+// which player is to move ("X" or "O"), a line with the board the player is
+// sent to ("-1 -1" for any board) and nine lines of nine squares each.
+func ReadGameState(r io.Reader) (*GameState, error) {
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) != 11 {
+		return nil, fmt.Errorf("expected 11 lines of board state, got %d", len(lines))
+	}
+
+	// First line, which player we're playing as.
+	state := &GameState{Player: 2, Rules: DefaultRules}
+	if lines[0] == "X" {
+		state.Player = 1
+	} else if lines[0] != "O" {
+		return nil, fmt.Errorf("unknown player %q", lines[0])
+	}
+
+	// Second line, which board we get to play on next
+	// -1, -1 means we can play on any board.
+	lineItems := strings.Fields(lines[1])
+	if len(lineItems) != 2 {
+		return nil, fmt.Errorf("expected a board position, got %q", lines[1])
+	}
+
+	tileX, errX := strconv.Atoi(lineItems[0])
+	tileY, errY := strconv.Atoi(lineItems[1])
+	if errX != nil || errY != nil || tileX < -1 || tileX > 2 || tileY < -1 || tileY > 2 {
+		return nil, fmt.Errorf("invalid board position %q", lines[1])
+	}
+	state.LastMove = Move{0, 0, tileX, tileY}
+
+	// The rest of the lines represent the current board state.
+	for rowIndex, row := range lines[2:] {
+		if len(row) != 9 {
+			return nil, fmt.Errorf("expected 9 squares on row %d, got %q", rowIndex, row)
+		}
+
+		for index, cell := range row {
+			if cell != EMPTY && cell != PLAYER_1_CONTROLLED && cell != PLAYER_2_CONTROLLED {
+				return nil, errors.New("squares must be one of '-', 'X' or 'O'")
+			}
+			state.Board[rowIndex/3][index/3][rowIndex%3][index%3] = int(cell)
+		}
+	}
+
+	return state, nil
+}
+
+// FormatPosition returns state in a single line notation, the same as
+// HackerRank's format with the lines separated by spaces and the rows by
+// slashes: the player to move, the board they are sent to ("-1 -1" for
+// any board) and the nine rows of squares, e.g. "O 0 2 X--------/---------/...".
+func FormatPosition(state *GameState) string {
+	var builder strings.Builder
+	x, y, _ := state.ForcedBoard()
+	fmt.Fprintf(&builder, "%c %d %d ", PlayerMark(state.Player), x, y)
+
+	for row := 0; row < 9; row++ {
+		if row > 0 {
+			builder.WriteByte('/')
+		}
+		for column := 0; column < 9; column++ {
+			builder.WriteByte(byte(state.Board[row/3][column/3][row%3][column%3]))
+		}
+	}
+
+	return builder.String()
+}
+
+// ParsePosition reads a position in the notation written by FormatPosition.
+func ParsePosition(position string) (*GameState, error) {
+	fields := strings.Fields(position)
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("expected a player, a board and the rows, got %q", position)
+	}
+
+	lines := append([]string{fields[0], fields[1] + " " + fields[2]}, strings.Split(fields[3], "/")...)
+	return ReadGameState(strings.NewReader(strings.Join(lines, "\n")))
+}
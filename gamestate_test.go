@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGameStateValidMoves(t *testing.T) {
+	state := NewGame()
+	if len(state.ValidMoves()) != 81 {
+		t.Error("The first player should be able to play on any of the 81 tiles!")
+	}
+
+	state.Play(&Move{0, 0, 1, 1})
+	if state.Player != 2 {
+		t.Error("After player 1 has moved it should be player 2's turn!")
+	}
+
+	for _, move := range state.ValidMoves() {
+		if move.BoardX != 1 || move.BoardY != 1 {
+			t.Error("Player 2 should be forced to play on board (1,1), not (", move.BoardX, ",", move.BoardY, ")")
+		}
+	}
+
+	// Win board (1,1) for player 1, after which nobody can be sent there.
+	state.Board[1][1][0][0] = PLAYER_1_CONTROLLED
+	state.Board[1][1][1][1] = PLAYER_1_CONTROLLED
+	state.Board[1][1][2][2] = PLAYER_1_CONTROLLED
+	if _, _, forced := state.ForcedBoard(); forced {
+		t.Error("A player sent to a board which is already won should be free to play on any board!")
+	}
+
+	if len(state.ValidMoves()) != 71 {
+		t.Error("Expected 71 valid moves, got", len(state.ValidMoves()))
+	}
+}
+
+func TestGameStateIsOver(t *testing.T) {
+	state := NewGame()
+	if state.IsOver() {
+		t.Error("A new game should not be over!")
+	}
+
+	var player1WonBoard TictactoeBoard
+	player1WonBoard.Clear()
+	player1WonBoard[0][0] = PLAYER_1_CONTROLLED
+	player1WonBoard[1][1] = PLAYER_1_CONTROLLED
+	player1WonBoard[2][2] = PLAYER_1_CONTROLLED
+
+	state.Board[0][0] = player1WonBoard
+	state.Board[1][1] = player1WonBoard
+	state.Board[2][2] = player1WonBoard
+	if !state.IsOver() {
+		t.Error("A game player 1 has won should be over!")
+	}
+}
+
+func TestReadGameState(t *testing.T) {
+	input := "O\n0 2\n--------X\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------"
+	state, err := ReadGameState(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("Failed to read a valid board state:", err)
+	}
+
+	if state.Player != 2 || state.LastMove.TileX != 0 || state.LastMove.TileY != 2 {
+		t.Error("Expected player 2 to be sent to board (0,2), got", state.Player, state.LastMove)
+	}
+
+	if state.Board[0][2][0][2] != PLAYER_1_CONTROLLED || len(state.Board.AllPossibleMoves()) != 80 {
+		t.Error("The board state was not read correctly!")
+	}
+
+	if _, err := ReadGameState(strings.NewReader("O\n0 2\n--------X\n")); err == nil {
+		t.Error("Reading a truncated board state should fail!")
+	}
+}
+
+func TestPositionNotation(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 0, 2})
+	state.Play(&Move{0, 2, 1, 1})
+
+	position := FormatPosition(state)
+	if expected := "X 1 1 --X------/-------O-/---------/---------/---------/---------/---------/---------/---------"; position != expected {
+		t.Errorf("FormatPosition() = %q, expected %q", position, expected)
+	}
+
+	parsed, err := ParsePosition(position)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Board != state.Board || parsed.Player != 1 || FormatPosition(parsed) != position {
+		t.Error("ParsePosition did not read back the position")
+	}
+
+	for _, broken := range []string{"", "X -1 -1", "X -1 -1 ---", "Y -1 -1 " + strings.Repeat("---------/", 8) + "---------"} {
+		if _, err := ParsePosition(broken); err == nil {
+			t.Errorf("Expected an error parsing %q", broken)
+		}
+	}
+}
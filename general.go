@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GeneralBoard is a game of ultimate Tic-Tac-Toe on a board of Size x Size
+// boards with Size x Size squares each, where InARow squares (or boards)
+// in a row, column or diagonal win a board (or the game). Size 3 with 3 in
+// a row is the standard game. The forced board rule is the same: a player
+// is sent to the board at the coordinates of the square the last move was
+// made on, unless it is won or full.
+type GeneralBoard struct {
+	Size     int
+	InARow   int
+	Squares  []int // Every square, indexed like moveSquare
+	Boards   []int // The winner of every board (EMPTY while undecided), row by row
+	LastMove Move
+	Player   int // The player (1 or 2) whose turn it is
+
+	filled []int // How many squares of every board are taken
+	winner int
+}
+
+// NewGeneralBoard returns the start of a game on size x size boards which
+// are won with inARow in a row, or an error if inARow does not fit.
+func NewGeneralBoard(size, inARow int) (*GeneralBoard, error) {
+	if inARow < 2 || inARow > size {
+		return nil, fmt.Errorf("cannot play %d in a row on %dx%d boards", inARow, size, size)
+	}
+
+	board := &GeneralBoard{
+		Size:     size,
+		InARow:   inARow,
+		Squares:  make([]int, size*size*size*size),
+		Boards:   make([]int, size*size),
+		LastMove: Move{-1, -1, -1, -1},
+		Player:   1,
+		filled:   make([]int, size*size),
+		winner:   EMPTY,
+	}
+	for i := range board.Squares {
+		board.Squares[i] = EMPTY
+	}
+	for i := range board.Boards {
+		board.Boards[i] = EMPTY
+	}
+
+	return board, nil
+}
+
+// ParseVariant reads the shape of a GeneralBoard written as "NxN:K", e.g.
+// "4x4:3" for 4x4 boards won with 3 in a row, and returns the new game.
+func ParseVariant(variant string) (*GeneralBoard, error) {
+	var size, columns, inARow int
+	if _, err := fmt.Sscanf(variant, "%dx%d:%d", &size, &columns, &inARow); err != nil || size != columns {
+		return nil, fmt.Errorf("invalid variant %q, expected NxN:K like 4x4:3", variant)
+	}
+
+	return NewGeneralBoard(size, inARow)
+}
+
+// lineWinner returns the player with inARow squares in a row, column or
+// diagonal of the size x size grid read by at, or EMPTY if there is none.
+func lineWinner(size, inARow int, at func(x, y int) int) int {
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			first := at(x, y)
+			if first == EMPTY {
+				continue
+			}
+
+			for _, direction := range directions {
+				endX, endY := x+(inARow-1)*direction[0], y+(inARow-1)*direction[1]
+				if endX < 0 || endX >= size || endY < 0 || endY >= size {
+					continue
+				}
+
+				length := 1
+				for length < inARow && at(x+length*direction[0], y+length*direction[1]) == first {
+					length++
+				}
+				if length == inARow {
+					return first
+				}
+			}
+		}
+	}
+
+	return EMPTY
+}
+
+// Square returns what the square move plays on holds.
+func (board *GeneralBoard) Square(move Move) int {
+	return board.Squares[moveSquare(move, board.Size)]
+}
+
+// isDead returns true if no more moves can be made on the board at x, y,
+// because it has been won or is full.
+func (board *GeneralBoard) isDead(x, y int) bool {
+	index := x*board.Size + y
+	return board.Boards[index] != EMPTY || board.filled[index] == board.Size*board.Size
+}
+
+// ForcedBoard returns the board the player to move has to play on, and
+// false if the player is free to play on any board.
+func (board *GeneralBoard) ForcedBoard() (int, int, bool) {
+	x, y := board.LastMove.TileX, board.LastMove.TileY
+	if x == -1 || board.isDead(x, y) {
+		return -1, -1, false
+	}
+
+	return x, y, true
+}
+
+// ToMove returns the player whose turn it is.
+func (board *GeneralBoard) ToMove() int {
+	return board.Player
+}
+
+// Moves returns the empty squares of the board the player to move is sent
+// to, or of every board which is not won if the player may play anywhere.
+func (board *GeneralBoard) Moves() []int {
+	if board.winner != EMPTY {
+		return nil
+	}
+
+	var squares []int
+	area := board.Size * board.Size
+	forcedX, forcedY, forced := board.ForcedBoard()
+	for boardIndex := 0; boardIndex < area; boardIndex++ {
+		if forced && boardIndex != forcedX*board.Size+forcedY {
+			continue
+		}
+		if board.Boards[boardIndex] != EMPTY {
+			continue
+		}
+
+		for square := boardIndex * area; square < (boardIndex+1)*area; square++ {
+			if board.Squares[square] == EMPTY {
+				squares = append(squares, square)
+			}
+		}
+	}
+
+	return squares
+}
+
+// PlaySquare plays on square for the player to move and passes the turn
+// to the other player, updating the winners of the board and the game.
+func (board *GeneralBoard) PlaySquare(square int) {
+	move := squareMove(square, board.Size)
+	boardIndex := move.BoardX*board.Size + move.BoardY
+	board.Squares[square] = PlayerMark(board.Player)
+	board.filled[boardIndex] += 1
+	board.LastMove = move
+	board.Player = Opponent(board.Player)
+
+	if board.Boards[boardIndex] != EMPTY {
+		return
+	}
+
+	first := boardIndex * board.Size * board.Size
+	board.Boards[boardIndex] = lineWinner(board.Size, board.InARow, func(x, y int) int {
+		return board.Squares[first+x*board.Size+y]
+	})
+	if board.Boards[boardIndex] != EMPTY {
+		board.winner = lineWinner(board.Size, board.InARow, func(x, y int) int {
+			return board.Boards[x*board.Size+y]
+		})
+	}
+}
+
+// Winner returns the player who has won the game, or EMPTY.
+func (board *GeneralBoard) Winner() int {
+	return board.winner
+}
+
+// IsOver returns true if either player has won the game, or if every
+// board is won or full (a tie).
+func (board *GeneralBoard) IsOver() bool {
+	if board.winner != EMPTY {
+		return true
+	}
+
+	for x := 0; x < board.Size; x++ {
+		for y := 0; y < board.Size; y++ {
+			if !board.isDead(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Clone returns a copy of board.
+func (board *GeneralBoard) Clone() Game {
+	clone := *board
+	clone.Squares = append([]int(nil), board.Squares...)
+	clone.Boards = append([]int(nil), board.Boards...)
+	clone.filled = append([]int(nil), board.filled...)
+	return &clone
+}
+
+// String returns the board as a grid of Size*Size rows of squares, with
+// the boards separated by '|' and '-'. Empty squares are drawn as '.'.
+func (board *GeneralBoard) String() string {
+	var builder strings.Builder
+	width := board.Size*board.Size + board.Size - 1
+	for row := 0; row < board.Size*board.Size; row++ {
+		if row > 0 && row%board.Size == 0 {
+			builder.WriteString(strings.Repeat("-", width) + "\n")
+		}
+
+		for column := 0; column < board.Size*board.Size; column++ {
+			if column > 0 && column%board.Size == 0 {
+				builder.WriteByte('|')
+			}
+
+			square := board.Square(Move{row / board.Size, column / board.Size, row % board.Size, column % board.Size})
+			if square == EMPTY {
+				square = '.'
+			}
+			builder.WriteByte(byte(square))
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// mustGeneralBoard returns a new GeneralBoard, failing the test if it cannot.
+func mustGeneralBoard(t *testing.T, size, inARow int) *GeneralBoard {
+	t.Helper()
+	board, err := NewGeneralBoard(size, inARow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return board
+}
+
+func TestGeneralBoardPerft(t *testing.T) {
+	// On 3x3 boards with 3 in a row, the moves are those of the standard game.
+	standard := mustGeneralBoard(t, 3, 3)
+	for depth, expected := range perftPositions[0].counts {
+		if nodes := GamePerft(standard, depth); nodes != expected {
+			t.Errorf("3x3:3: GamePerft(%d) = %d, expected %d", depth, nodes, expected)
+		}
+	}
+
+	// Every first move sends the opponent to a board with 16 empty squares,
+	// except for the 16 moves sending them back to the board played on.
+	large := mustGeneralBoard(t, 4, 3)
+	for depth, expected := range []uint64{1, 256, 16*15 + 240*16} {
+		if nodes := GamePerft(large, depth); nodes != expected {
+			t.Errorf("4x4:3: GamePerft(%d) = %d, expected %d", depth, nodes, expected)
+		}
+	}
+}
+
+func TestGeneralBoardWinner(t *testing.T) {
+	// X takes the diagonal (0,1) (1,2) (2,3) of board (0,0), while O plays
+	// on the boards X sends it to, which sends X back to board (0,0).
+	moves := []Move{
+		{0, 0, 0, 1}, {0, 1, 0, 0},
+		{0, 0, 1, 2}, {1, 2, 0, 0},
+		{0, 0, 2, 3},
+	}
+
+	board := mustGeneralBoard(t, 4, 3)
+	for _, move := range moves {
+		if board.Boards[0] != EMPTY {
+			t.Fatal("Board (0,0) was won too early, before", move)
+		}
+		board.PlaySquare(moveSquare(move, 4))
+	}
+
+	if board.Boards[0] != PLAYER_1_CONTROLLED {
+		t.Fatalf("Expected X to win board (0,0), got %c\n%s", board.Boards[0], board)
+	}
+	if board.Winner() != EMPTY || board.IsOver() {
+		t.Error("Expected the game to go on with a single board won")
+	}
+
+	// O is sent to board (2,3), and may play on any of its 16 squares.
+	if x, y, forced := board.ForcedBoard(); !forced || x != 2 || y != 3 || len(board.Moves()) != 16 {
+		t.Error("Expected O to be sent to board (2,3), got", x, y, forced, len(board.Moves()))
+	}
+
+	// With boards (1,0) and (2,0) won too, winning board (0,0) wins the game.
+	board = mustGeneralBoard(t, 4, 3)
+	board.Boards[1*4+0] = PLAYER_1_CONTROLLED
+	board.Boards[2*4+0] = PLAYER_1_CONTROLLED
+	for _, move := range moves {
+		board.PlaySquare(moveSquare(move, 4))
+	}
+
+	if board.Winner() != PLAYER_1_CONTROLLED || !board.IsOver() || board.Moves() != nil {
+		t.Errorf("Expected X to win the game, got %c", board.Winner())
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	board, err := ParseVariant("4x4:3")
+	if err != nil || board.Size != 4 || board.InARow != 3 || len(board.Squares) != 256 {
+		t.Fatal("Failed to parse the 4x4:3 variant:", board, err)
+	}
+
+	for _, variant := range []string{"4x3:3", "4x4:5", "3x3:1", "big"} {
+		if _, err := ParseVariant(variant); err == nil {
+			t.Errorf("Expected the variant %q to be rejected", variant)
+		}
+	}
+}
+
+func TestGeneralBoardString(t *testing.T) {
+	board := mustGeneralBoard(t, 2, 2)
+	board.PlaySquare(moveSquare(Move{1, 0, 0, 1}, 2))
+
+	expected := "..|..\n..|..\n-----\n.X|..\n..|..\n"
+	if board.String() != expected {
+		t.Errorf("Expected the board\n%s\ngot\n%s", expected, board)
+	}
+	if strings.Count(board.String(), "\n") != 5 {
+		t.Error("Expected 4 rows and a separator")
+	}
+}
+
+func TestRunVariantArena(t *testing.T) {
+	config := DefaultSearchConfig
+	config.MaxPlayouts = 50
+
+	result, err := RunVariantArena("4x4:3", "montecarlo,random", config, 2)
+	if err != nil || !strings.HasPrefix(result, "montecarlo vs random on 4x4:3: +") {
+		t.Error("Unexpected arena result:", result, err)
+	}
+
+	if _, err := RunVariantArena("4x4:3", "uct,random", config, 2); err == nil {
+		t.Error("Expected a bot that cannot play variants to be rejected")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+// undoRecord is what GameHistory.Unmake needs to take a move back.
+type undoRecord struct {
+	move     Move
+	lastMove Move        // The GameState's LastMove before the move
+	status   BoardStatus // The status of the board before the move
+}
+
+// GameHistory is a GameState which remembers the moves made on it, so that
+// they can be taken back and made again without copying the board. Its Play
+// and PlaySquare make moves like Make. The status of the board is kept up
+// to date as moves are made, and restored as they are taken back. The
+// GameState itself holds no history, so that it can still be used as the
+// key of a transposition table.
+type GameHistory struct {
+	GameState
+	status BoardStatus
+	made   []undoRecord
+	undone []Move // The moves taken back, the next one to Redo last
+}
+
+// NewGameHistory returns a GameHistory starting from state, with no
+// moves to take back yet.
+func NewGameHistory(state *GameState) *GameHistory {
+	return &GameHistory{GameState: *state, status: NewBoardStatus(&state.Board)}
+}
+
+// Make plays move for the player to move, like the GameState's Play, and
+// remembers it. The moves which were taken back can no longer be redone.
+func (history *GameHistory) Make(move *Move) {
+	history.undone = history.undone[:0]
+	history.play(move)
+}
+
+// play makes move and remembers it.
+func (history *GameHistory) play(move *Move) {
+	history.made = append(history.made, undoRecord{*move, history.LastMove, history.status})
+	history.GameState.Play(move)
+	history.status.Update(&history.Board, move)
+}
+
+// Play makes move like Make, rather than like the GameState's Play, which
+// would leave the status of the board and the moves made behind.
+func (history *GameHistory) Play(move *Move) {
+	history.Make(move)
+}
+
+// PlaySquare plays the move on square, like Play.
+func (history *GameHistory) PlaySquare(square int) {
+	move := squareMove(square, 3)
+	history.Make(&move)
+}
+
+// Unmake takes back the last move made, restoring the board, the board the
+// player to move is sent to and whose turn it is. It returns the move, or
+// nil if no move has been made.
+func (history *GameHistory) Unmake() *Move {
+	if len(history.made) == 0 {
+		return nil
+	}
+
+	record := history.made[len(history.made)-1]
+	history.made = history.made[:len(history.made)-1]
+	history.undone = append(history.undone, record.move)
+
+	move := record.move
+	history.Board[move.BoardX][move.BoardY][move.TileX][move.TileY] = EMPTY
+	history.LastMove = record.lastMove
+	history.status = record.status
+	history.Player = Opponent(history.Player)
+	return &move
+}
+
+// Redo makes the last move taken back again and returns it, or nil if
+// there is none.
+func (history *GameHistory) Redo() *Move {
+	if len(history.undone) == 0 {
+		return nil
+	}
+
+	move := history.undone[len(history.undone)-1]
+	history.undone = history.undone[:len(history.undone)-1]
+	history.play(&move)
+	return &move
+}
+
+// Winner returns the same as the GameState's Winner, from the status of
+// the board.
+func (history *GameHistory) Winner() int {
+	return history.Rules.StatusWinner(&history.status)
+}
+
+// IsOver returns the same as the GameState's IsOver, from the status of
+// the board.
+func (history *GameHistory) IsOver() bool {
+	if history.Winner() != EMPTY {
+		return true
+	}
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if history.status.IsOpen(i, j) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ValidMoves returns the same moves as the GameState's ValidMoves, from
+// the status of the board.
+func (history *GameHistory) ValidMoves() []*Move {
+	return history.status.ValidMoves(&history.GameState)
+}
+
+// MovesMade returns the moves made, in the order they were made.
+func (history *GameHistory) MovesMade() []Move {
+	moves := make([]Move, len(history.made))
+	for i, record := range history.made {
+		moves[i] = record.move
+	}
+
+	return moves
+}
+
+// MovesUndone returns the moves taken back, in the order Redo makes them again.
+func (history *GameHistory) MovesUndone() []Move {
+	moves := make([]Move, len(history.undone))
+	for i, move := range history.undone {
+		moves[len(moves)-1-i] = move
+	}
+
+	return moves
+}
@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestGameHistoryUnmake(t *testing.T) {
+	start := NewGame()
+	history := NewGameHistory(start)
+	if history.Unmake() != nil || history.Redo() != nil {
+		t.Fatal("Expected nothing to undo or redo in a new game")
+	}
+
+	moves := []Move{{1, 1, 0, 2}, {0, 2, 1, 1}, {1, 1, 1, 1}}
+	var states []GameState
+	for i := range moves {
+		states = append(states, history.GameState)
+		history.Make(&moves[i])
+	}
+	end := history.GameState
+
+	for i := len(moves) - 1; i >= 0; i-- {
+		if move := history.Unmake(); move == nil || *move != moves[i] {
+			t.Fatalf("Expected to take back %v, got %v", moves[i], move)
+		}
+		if history.GameState != states[i] || history.status != NewBoardStatus(&history.Board) {
+			t.Errorf("Taking back %v did not restore the position:\n%s", moves[i], FormatPosition(&history.GameState))
+		}
+	}
+	if history.GameState != *start || history.Unmake() != nil {
+		t.Error("Expected to be back at the start of the game")
+	}
+
+	for i := range moves {
+		if move := history.Redo(); move == nil || *move != moves[i] {
+			t.Fatalf("Expected to redo %v, got %v", moves[i], move)
+		}
+	}
+	if history.GameState != end || history.Redo() != nil {
+		t.Error("Expected to be back at the end of the game")
+	}
+}
+
+func TestGameHistoryForcedBoard(t *testing.T) {
+	history := NewGameHistory(NewGame())
+	history.Make(&Move{1, 1, 0, 2})
+	history.Make(&Move{0, 2, 1, 1})
+
+	history.Unmake()
+	if x, y, forced := history.ForcedBoard(); !forced || x != 0 || y != 2 || history.Player != 2 {
+		t.Errorf("Expected O to be sent to board 0 2 again, got %d %d (%v), player %d", x, y, forced, history.Player)
+	}
+
+	history.Unmake()
+	if _, _, forced := history.ForcedBoard(); forced || history.Player != 1 {
+		t.Error("Expected X to play anywhere at the start of the game")
+	}
+}
+
+func TestGameHistoryMakeClearsRedo(t *testing.T) {
+	history := NewGameHistory(NewGame())
+	history.Make(&Move{1, 1, 0, 2})
+	history.Make(&Move{0, 2, 1, 1})
+	history.Unmake()
+
+	if undone := history.MovesUndone(); len(undone) != 1 || undone[0] != (Move{0, 2, 1, 1}) {
+		t.Error("Expected 0 2 1 1 to be redone next, got", undone)
+	}
+
+	history.Make(&Move{0, 2, 0, 0})
+	if history.Redo() != nil || len(history.MovesUndone()) != 0 {
+		t.Error("Expected a new move to drop the moves taken back")
+	}
+	if made := history.MovesMade(); len(made) != 2 || made[1] != (Move{0, 2, 0, 0}) {
+		t.Error("Expected the moves 1 1 0 2 and 0 2 0 0, got", made)
+	}
+}
+
+func TestGameHistoryPlay(t *testing.T) {
+	// Play and PlaySquare must keep the status of the board up to date
+	// and remember the moves, like Make.
+	history := NewGameHistory(NewGame())
+	history.Play(&Move{0, 0, 0, 0})
+	history.Play(&Move{0, 0, 1, 1})
+	history.PlaySquare(moveSquare(Move{1, 1, 0, 1}, 3))
+	history.Play(&Move{0, 1, 0, 0})
+	history.Play(&Move{0, 0, 0, 2})
+
+	if history.status != NewBoardStatus(&history.Board) {
+		t.Error("The status of the board is out of date after", history.MovesMade())
+	}
+	if moves := history.ValidMoves(); len(moves) != len(history.GameState.ValidMoves()) {
+		t.Error("Expected the moves", history.GameState.ValidMoves(), "got", moves)
+	}
+
+	if move := history.Unmake(); *move != (Move{0, 0, 0, 2}) || len(history.MovesMade()) != 4 {
+		t.Error("Expected Play to remember the moves, took back", move, "leaving", history.MovesMade())
+	}
+}
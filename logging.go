@@ -0,0 +1,19 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// setupLogging makes the default slog logger write to w (stderr, as
+// HackerRank reads the bot's moves from stdout) at the given level:
+// "debug", "info", "warn" or "error".
+func setupLogging(w io.Writer, level string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel})))
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetupLogging(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buffer bytes.Buffer
+	if err := setupLogging(&buffer, "info"); err != nil {
+		t.Fatal("Failed to set up logging:", err)
+	}
+
+	analysis := &Analysis{
+		Candidates: []CandidateMove{{Move{1, 1, 0, 0}, 30, 0.5, 0.5, 0, 0.75}},
+		Playouts:   1000,
+		TimeUsed:   2 * time.Second,
+		TreeSize:   42,
+	}
+	slog.Debug("This should not be logged")
+	slog.Info("Made a move", "search", analysis)
+
+	output := buffer.String()
+	if strings.Contains(output, "This should not be logged") {
+		t.Error("Debug messages should not be logged at the info level!")
+	}
+
+	for _, expected := range []string{"search.playouts=1000", "search.playoutsPerSec=500", "search.treeSize=42", "search.confidence=0.75", `search.move="1 1 0 0"`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the log to contain %q:\n%s", expected, output)
+		}
+	}
+
+	if setupLogging(&buffer, "verbose") == nil {
+		t.Error("Setting up logging with an unknown level should fail!")
+	}
+}
+
+func TestTreeAnalysisLogValue(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buffer bytes.Buffer
+	if err := setupLogging(&buffer, "info"); err != nil {
+		t.Fatal("Failed to set up logging:", err)
+	}
+
+	config := testSearchConfig
+	config.MaxPlayouts = 100
+	tree := NewSearchTree(NewGame(), config)
+	tree.Search(nil)
+
+	slog.Info("Made a move", "search", treeAnalysis{tree})
+	expected := fmt.Sprintf("search.treeSize=%d", tree.root.size())
+	if !strings.Contains(buffer.String(), expected) {
+		t.Errorf("Expected the log to contain %q:\n%s", expected, buffer.String())
+	}
+}
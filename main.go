@@ -2,10 +2,16 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,47 +21,560 @@ const (
 	TIME_TO_THINK       = 5.9 // How long the Monte Carlo bot can think before making it's move (seconds)
 )
 
+var (
+	persistent  = flag.Bool("persistent", false, "Keep playing the same game over stdin/stdout, thinking on the opponent's time")
+	analyze     = flag.Bool("analyze", false, "Print an analysis of the position instead of just the next move")
+	httpAddr    = flag.String("http", "", "Serve the HTTP API and the browser UI on this address (e.g. :8080) instead of reading stdin")
+	perftDepth  = flag.Int("perft", 0, "Count the positions reached after this many moves from the board state instead of moving")
+	threshold   = flag.Int("solver-threshold", DefaultSearchConfig.SolverThreshold, "Solve positions with at most this many empty squares exactly (0 to never)")
+	playouts    = flag.Int("playouts", 0, "Stop searching after this many playouts (0 means only TIME_TO_THINK counts)")
+	rave        = flag.Float64("rave", DefaultSearchConfig.RaveEquivalence, "RAVE equivalence parameter for the tree search (0 turns RAVE off)")
+	thinkTime   = flag.Float64("think", TIME_TO_THINK, "How many seconds the tree search may think about a move")
+	policyName  = flag.String("policy", "uniform", "Playout policy of the tree search (uniform or heavy)")
+	epsilon     = flag.Float64("epsilon", DEFAULT_EPSILON, "Share of the heavy playout policy's moves made at random")
+	selection   = flag.String("select", "default", "How to pick the move to play once the search is over (most-visits, mean, robust-max, lcb or legacy)")
+	lossWeight  = flag.Float64("loss-weight", DefaultSearchConfig.LegacyLossWeight, "How much more a loss weighs than a win with -select legacy")
+	arenaGames  = flag.Int("arena", 0, "Play this many games between the two -bots instead of reading stdin")
+	variant     = flag.String("variant", "", "Play the -arena games on NxN boards won with K in a row, written NxN:K (e.g. 4x4:3), or on boards nested D levels deep, written levels:D, with the random and montecarlo bots")
+	arenaBots   = flag.String("bots", "rave,uct", "The two bots to play in the -arena or -selfplay, separated by a comma")
+	selfPlay    = flag.Int("selfplay", 0, "Play this many games between the two -bots and write every position to the -out dataset")
+	outPath     = flag.String("out", "samples.bin", "The dataset file -selfplay writes to")
+	format      = flag.String("format", "binary", "The format of the -selfplay dataset (binary or jsonl)")
+	augment     = flag.Bool("augment", true, "Write every -selfplay position under all 8 symmetries of the board")
+	botName     = flag.String("bot", "montecarlo", "The bot that picks the move for a board state read from stdin")
+	maxDepth    = flag.Int("depth", 0, "How many moves ahead the alphabeta bot looks (0 means only -think counts)")
+	weights     = flag.String("weights", "", "Evaluation weights file for the alphabeta bot and the tree search, or to -train")
+	trainGames  = flag.Int("train", 0, "Learn evaluation weights from this many self-play games of -train-bot and write them to -weights")
+	trainBot    = flag.String("train-bot", "policy", "The bot playing the -train games")
+	epochs      = flag.Int("epochs", 20, "How many passes over the training data -train and -train-net make")
+	networkPath = flag.String("net", "", "Neural network weights file for the puct bot, or to write with -train-net")
+	trainNet    = flag.String("train-net", "", "Train the -net network (a new one if the file does not exist) on the self-play samples in this file")
+	bookPath    = flag.String("book", "", "Opening book file the bots play their first moves from, or to write with -build-book")
+	buildBook   = flag.Int("build-book", 0, "Build an opening book of the positions with fewer than this many moves made, searched by the -bot, and write it to -book")
+	showBook    = flag.Bool("show-book", false, "Print the positions and moves of the -book")
+	minePuzzles = flag.String("mine-puzzles", "", "Find the puzzles in the positions of this self-play dataset and write them to -puzzles")
+	puzzlePath  = flag.String("puzzles", "puzzles.txt", "The puzzle file -mine-puzzles writes to and -grade-puzzles reads")
+	puzzlePlies = flag.Int("puzzle-plies", DEFAULT_PUZZLE_PLIES, "Only look for puzzles won within this many moves of both players")
+	grade       = flag.Bool("grade-puzzles", false, "Give every puzzle in -puzzles to the -bot and print how many it solves")
+	svgPath     = flag.String("svg", "", "Draw the board state read from stdin as an SVG image to this file, with -analyze the search's candidate moves too")
+	pngPath     = flag.String("png", "", "Draw the board state read from stdin as a PNG image to this file, like -svg")
+	recordPath  = flag.String("record", "", "Play a game between the two -bots and write its moves to this file, one per line")
+	gifPath     = flag.String("gif", "", "Draw the game recorded in the -game file as an animated GIF to this file")
+	gamePath    = flag.String("game", "game.txt", "The game record -gif draws, and the terminal UI saves and opens")
+	runUI       = flag.Bool("tui", false, "Analyze and play games against the -bot in a full-screen terminal UI")
+	rulesNames  = flag.String("rules", "standard", "Play and analyze games under these rule variants, separated by commas: misere, most-boards and free (no forced board)")
+	logLevel    = flag.String("log-level", "warn", "Log messages of this level and above to stderr (debug, info, warn or error)")
+)
+
 // main, in this case, reads in the board state from HackerRank
 // and emits the next Move as a space separated string.
 func main() {
-	reader := bufio.NewReader(os.Stdin)
-	var playerNumber int
-	var lastMove *Move
-	var board UltimateBoard
-	var rowIndex int
-
-	for {
-		line, err := reader.ReadString('\n')
-		lineItems := strings.Fields(line)
-
-		if playerNumber == 0 {
-			// First line, we're reading in which player we're playing as.
-			if lineItems[0] == "X" {
-				playerNumber = 1
-			} else {
-				playerNumber = 2
+	flag.Parse()
+	if err := setupLogging(os.Stderr, *logLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	DefaultSearchConfig.SolverThreshold = *threshold
+	DefaultSearchConfig.MaxPlayouts = *playouts
+	DefaultSearchConfig.RaveEquivalence = *rave
+	DefaultSearchConfig.ThinkTime = time.Duration(*thinkTime * float64(time.Second))
+	if DefaultSearchConfig.ThinkTime <= 0 && DefaultSearchConfig.MaxPlayouts == 0 {
+		fmt.Fprintln(os.Stderr, "-think has to be positive unless -playouts limits the search")
+		os.Exit(2)
+	}
+	DefaultSearchConfig.LegacyLossWeight = *lossWeight
+	strategy, err := ParseSelectionStrategy(*selection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	DefaultSearchConfig.Selection = strategy
+	if DefaultRules, err = ParseRules(*rulesNames); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *policyName != "uniform" {
+		policy, err := NewPlayoutPolicy(*policyName, *epsilon)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		DefaultSearchConfig.Policy = policy
+	}
+
+	DefaultSearchConfig.MaxDepth = *maxDepth
+	if *weights != "" && *trainGames == 0 {
+		evaluator, err := LoadEvaluator(*weights)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load the weights:", err)
+			os.Exit(2)
+		}
+		DefaultEvaluator = evaluator
+		DefaultSearchConfig.Evaluator = evaluator
+	}
+
+	if *trainNet != "" {
+		if err := trainNetwork(*trainNet, *networkPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *networkPath != "" {
+		network, err := LoadNetwork(*networkPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load the network:", err)
+			os.Exit(2)
+		}
+		DefaultSearchConfig.Network = network
+	}
+
+	if *buildBook > 0 {
+		if err := writeBook(*botName, *buildBook, *bookPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *bookPath != "" {
+		book, err := LoadBook(*bookPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load the opening book:", err)
+			os.Exit(2)
+		}
+		DefaultSearchConfig.Book = book
+	}
+
+	if *showBook {
+		if DefaultSearchConfig.Book == nil {
+			fmt.Fprintln(os.Stderr, "-show-book needs the -book file to show")
+			os.Exit(2)
+		}
+		fmt.Print(DefaultSearchConfig.Book)
+		return
+	}
+
+	if *minePuzzles != "" {
+		if err := writePuzzles(*minePuzzles, *puzzlePlies, *puzzlePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *grade {
+		result, err := gradePuzzles(*botName, *puzzlePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(result)
+		return
+	}
+
+	if *trainGames > 0 {
+		if err := train(*trainBot, *trainGames, *weights); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *persistent {
+		if err := runPersistent(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *arenaGames > 0 && *variant != "" {
+		result, err := RunVariantArena(*variant, *arenaBots, DefaultSearchConfig, *arenaGames)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Print(result)
+		return
+	}
+
+	if *arenaGames > 0 {
+		result, err := RunArena(*arenaBots, DefaultSearchConfig, *arenaGames)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Print(result)
+		return
+	}
+
+	if *selfPlay > 0 {
+		summary, err := RunSelfPlay(*arenaBots, DefaultSearchConfig, *selfPlay, *outPath, *format, *augment)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(summary)
+		return
+	}
+
+	if *httpAddr != "" {
+		if err := Serve(*httpAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *runUI {
+		newAnalyst, ok := Analysts[*botName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown bot %q, expected one of %s\n", *botName, strings.Join(BotNames(), ", "))
+			os.Exit(2)
+		}
+
+		if err := RunTUI(os.Stdin, DefaultSearchConfig, newAnalyst(DefaultSearchConfig), *gamePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *recordPath != "" {
+		if err := writeGameRecord(*arenaBots, *recordPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *gifPath != "" {
+		if err := writeGIF(*gamePath, *gifPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	state, err := ReadGameState(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read the board state:", err)
+		os.Exit(1)
+	}
+
+	if *perftDepth > 0 {
+		fmt.Print(PerftDivide(state, *perftDepth))
+		return
+	}
+
+	if *svgPath != "" || *pngPath != "" {
+		if err := writePictures(state, *svgPath, *pngPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *analyze {
+		analysis := TreeSearchAnalysis(state, DefaultSearchConfig)
+		options := RenderOptions{LastMove: &state.LastMove, Candidates: analysis.Candidates}
+		fmt.Print(RenderText(&state.Board, options, useColors(os.Stdout)))
+		fmt.Print(analysis)
+		return
+	}
+
+	newBot, ok := Bots[*botName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown bot %q, expected one of %s\n", *botName, strings.Join(BotNames(), ", "))
+		os.Exit(2)
+	}
+
+	// Print the bot's next move in HackerRank's preferred format.
+	move := newBot(DefaultSearchConfig)(state)
+	fmt.Printf("%d %d %d %d\n", move.BoardX, move.BoardY, move.TileX, move.TileY)
+}
+
+// runPersistent plays a whole game over in and out, using an Engine which
+// ponders while it waits for the opponent. The first line says which player
+// the bot is playing as ("X" or "O"). Every line after that is either the
+// opponent's move, in the same "boardX boardY tileX tileY" format the bot
+// emits its moves in, or "start" if the bot makes the first move of the game.
+// It returns an error if the opponent makes an illegal move.
+func runPersistent(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+
+	playerNumber := 2
+	if strings.TrimSpace(scanner.Text()) == "X" {
+		playerNumber = 1
+	}
+
+	state := NewGame()
+	engine := NewEngine(DefaultSearchConfig)
+	defer engine.StopPondering()
+
+	for scanner.Scan() {
+		lineItems := strings.Fields(scanner.Text())
+		if len(lineItems) == 4 {
+			var numbers [4]int
+			for i, item := range lineItems {
+				var err error
+				if numbers[i], err = strconv.Atoi(item); err != nil {
+					return fmt.Errorf("invalid move from the opponent %q: %v", scanner.Text(), err)
+				}
 			}
-		} else if lastMove == nil {
-			// Second line, reading in which board we get to play on next
-			// -1, -1 means we can play on any board.
-			lastMoveTileX, _ := strconv.Atoi(lineItems[0])
-			lastMoveTileY, _ := strconv.Atoi(lineItems[1])
-			lastMove = &Move{0, 0, lastMoveTileX, lastMoveTileY}
-		} else {
-			// Now reading in the lines representing the
-			// current board state.
-			row := lineItems[0]
-			for index, cell := range row {
-				board[rowIndex/3][index/3][rowIndex%3][index%3] = int(cell)
+
+			opponentMove := Move{numbers[0], numbers[1], numbers[2], numbers[3]}
+			if state.Player == playerNumber || !isValidMove(state, &opponentMove) {
+				return fmt.Errorf("illegal move from the opponent: %q", scanner.Text())
 			}
-			rowIndex += 1
+			state.Play(&opponentMove)
 		}
 
+		if state.Player != playerNumber || state.IsOver() {
+			continue
+		}
+
+		move := engine.Think(state)
+		if move == nil {
+			return fmt.Errorf("the engine found no move to make")
+		}
+		fmt.Fprintf(out, "%d %d %d %d\n", move.BoardX, move.BoardY, move.TileX, move.TileY)
+		state.Play(move)
+		engine.Ponder()
+	}
+
+	return scanner.Err()
+}
+
+// train plays games self-play games of the bot called botName, learns
+// evaluation weights from them and writes them to the file at path, or
+// to stdout if path is empty.
+func train(botName string, games int, path string) error {
+	newBot, ok := Bots[botName]
+	if !ok {
+		return fmt.Errorf("unknown bot %q, expected one of %s", botName, strings.Join(BotNames(), ", "))
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	positions := SelfPlayPositions(newBot(DefaultSearchConfig), games, random)
+	evaluator := TrainEvaluator(positions, *epochs, TRAINING_RATE, random)
+	slog.Info("Trained the evaluator", "games", games, "positions", len(positions), "loss", evaluator.Loss(positions))
+
+	if path == "" {
+		return evaluator.Save(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := evaluator.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// trainNetwork trains the network in the file at path, or a new one if
+// there is no such file, on the samples in samplesPath and saves it to path.
+func trainNetwork(samplesPath, path string) error {
+	if path == "" {
+		return fmt.Errorf("-train-net needs a -net file to save the network to")
+	}
+
+	samples, err := LoadTrainingSamples(samplesPath)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("%s holds no samples to train the network on", samplesPath)
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	network, err := LoadNetwork(path)
+	if errors.Is(err, os.ErrNotExist) {
+		network = NewNetwork(DefaultHiddenLayers, random)
+	} else if err != nil {
+		return err
+	}
+
+	valueLoss, policyLoss := network.Train(samples, *epochs, NETWORK_LEARNING_RATE, random)
+	slog.Info("Trained the network", "samples", len(samples), "valueLoss", valueLoss, "policyLoss", policyLoss)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := network.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeBook builds an opening book of the positions with fewer than plies
+// moves made, searched by the bot called botName, and saves it to path.
+func writeBook(botName string, plies int, path string) error {
+	if path == "" {
+		return fmt.Errorf("-build-book needs a -book file to save the book to")
+	}
+
+	newAnalyst, ok := Analysts[botName]
+	if !ok {
+		return fmt.Errorf("unknown bot %q, expected one of %s", botName, strings.Join(BotNames(), ", "))
+	}
+
+	start := time.Now()
+	book := BuildBook(newAnalyst(DefaultSearchConfig), plies)
+	slog.Info("Built the opening book", "positions", book.Len(), "plies", plies, "timeUsed", time.Since(start))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := book.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writePuzzles finds the puzzles won within plies moves in the positions of
+// the self-play dataset at samplesPath and saves them to path.
+func writePuzzles(samplesPath string, plies int, path string) error {
+	samples, err := LoadTrainingSamples(samplesPath)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("%s holds no positions to mine puzzles from", samplesPath)
+	}
+
+	states := make([]GameState, len(samples))
+	for i := range samples {
+		states[i] = samples[i].State
+	}
+	puzzles := MinePuzzles(states, plies)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := SavePuzzles(file, puzzles); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// gradePuzzles gives the puzzles in the file at path to the bot called
+// botName and returns how many of them it solved.
+func gradePuzzles(botName, path string) (PuzzleResult, error) {
+	newAnalyst, ok := Analysts[botName]
+	if !ok {
+		return PuzzleResult{}, fmt.Errorf("unknown bot %q, expected one of %s", botName, strings.Join(BotNames(), ", "))
+	}
+
+	puzzles, err := LoadPuzzles(path)
+	if err != nil {
+		return PuzzleResult{}, err
+	}
+
+	return GradePuzzles(newAnalyst(DefaultSearchConfig), puzzles), nil
+}
+
+// writePictures draws state as an SVG image to the file at svgPath and as a
+// PNG image to the file at pngPath, leaving out the formats without a path,
+// with the candidate moves of a search of the position if -analyze is set.
+func writePictures(state *GameState, svgPath, pngPath string) error {
+	if svgPath != "" && svgPath == pngPath {
+		return fmt.Errorf("-svg and -png cannot both write to %s", svgPath)
+	}
+
+	options := RenderOptions{LastMove: &state.LastMove}
+	if *analyze && !state.IsOver() {
+		analysis := TreeSearchAnalysis(state, DefaultSearchConfig)
+		fmt.Print(analysis)
+		options.Candidates = analysis.Candidates
+		options.Arrows = 3
+	}
+
+	outputs := []struct {
+		path   string
+		render func(io.Writer, *UltimateBoard, RenderOptions) error
+	}{
+		{svgPath, RenderSVG},
+		{pngPath, RenderPNG},
+	}
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+
+		file, err := os.Create(output.path)
 		if err != nil {
-			// This is the last line -> print the bot's next move in HackerRank's preferred format.
-			move := MonteCarloBot(playerNumber, lastMove, &board)
-			fmt.Printf("%d %d %d %d\n", move.BoardX, move.BoardY, move.TileX, move.TileY)
-			break
+			return err
+		}
+
+		if err := output.render(file, &state.Board, options); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
 		}
 	}
+
+	return nil
+}
+
+// writeGameRecord plays a game between the two bots named in names,
+// separated by a comma, and saves its record to path.
+func writeGameRecord(names, path string) error {
+	analysts, err := newAnalysts(strings.Split(names, ","), DefaultSearchConfig)
+	if err != nil {
+		return err
+	}
+
+	record := RecordGame(analystBot(analysts[0]), analystBot(analysts[1]))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := record.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeGIF draws the game recorded in the file at gamePath as an animated
+// GIF to the file at path.
+func writeGIF(gamePath, path string) error {
+	record, err := LoadGameRecord(gamePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := RenderGIF(file, record); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// SearchConfig holds the tunable parameters of the tree search.
+type SearchConfig struct {
+	ThinkTime   time.Duration // How long the search may think about a move
+	Exploration float64       // The UCT exploration constant
+	MaxPlayouts int           // Stop after this many playouts (0 means no limit)
+
+	// RaveEquivalence is the number of visits at which a move's own
+	// statistics and its All-Moves-As-First statistics weigh the same
+	// (0 turns RAVE off).
+	RaveEquivalence float64
+
+	// Selection decides which move to play once the search is over.
+	// SELECT_LEGACY scores moves as (wins - LegacyLossWeight * losses) /
+	// visits, with each result divided by the number of moves until the
+	// game ended if LegacyLengthWeighting is set.
+	Selection             SelectionStrategy
+	LegacyLossWeight      float64
+	LegacyLengthWeighting bool
+
+	// Policy picks the moves in the simulated games (nil means UniformPolicy).
+	Policy PlayoutPolicy
+
+	// Evaluator, if set, estimates the result of the positions the tree
+	// search reaches instead of simulating the rest of the game, and
+	// evaluates the positions at the alpha-beta search's horizon.
+	Evaluator *Evaluator
+
+	// Network guides the "puct" bot's search with its move priors and
+	// evaluates the positions it reaches (nil means the Evaluator, with
+	// the same prior for every move).
+	Network ValuePolicy
+
+	// MaxDepth limits how many moves ahead the alpha-beta search looks
+	// (0 means it deepens for as long as ThinkTime allows).
+	MaxDepth int
+
+	// Solve positions with at most this many empty squares left
+	// exactly instead of searching them (0 turns the solver off).
+	SolverThreshold int
+
+	// Book gives the moves to play in the positions it knows
+	// without searching them (nil means no opening book).
+	Book *Book
+}
+
+// deadline returns when a search started at start runs out of ThinkTime,
+// or the zero time if ThinkTime does not limit it.
+func (config *SearchConfig) deadline(start time.Time) time.Time {
+	if config.ThinkTime <= 0 {
+		return time.Time{}
+	}
+
+	return start.Add(config.ThinkTime)
+}
+
+// remaining returns config with the time since start taken off ThinkTime,
+// for a search which only starts once the solver has given up. At least
+// a nanosecond is left, as a ThinkTime of 0 would not limit the search.
+func (config SearchConfig) remaining(start time.Time) SearchConfig {
+	if config.ThinkTime > 0 {
+		config.ThinkTime = max(config.ThinkTime-time.Since(start), time.Nanosecond)
+	}
+
+	return config
+}
+
+// DEFAULT_RAVE_EQUIVALENCE is the RaveEquivalence used by the "rave" bot
+// unless told otherwise.
+const DEFAULT_RAVE_EQUIVALENCE = 500
+
+// DefaultSearchConfig is the configuration used unless told otherwise.
+var DefaultSearchConfig = SearchConfig{
+	ThinkTime:       time.Duration(TIME_TO_THINK * float64(time.Second)),
+	Exploration:     math.Sqrt2,
+	SolverThreshold: 20,
+
+	LegacyLossWeight:      2.0,
+	LegacyLengthWeighting: true,
+}
+
+// treeNode is a single position in the search tree. Wins and results are
+// counted from the point of view of player, the player who made move.
+type treeNode struct {
+	move     Move
+	player   int
+	parent   *treeNode
+	children []*treeNode
+	untried  []*Move
+	moveStats
+
+	// All-Moves-As-First statistics: the results of the playouts
+	// below the parent in which player made move at any later point.
+	amafVisits float64
+	amafScore  float64 // A win counts as 1 and a tie as 0.5
+
+	// Once the result of the position is certain, proven is set and
+	// result is SOLVED_WIN, SOLVED_DRAW or SOLVED_LOSS.
+	proven bool
+	result int
+}
+
+// newTreeNode creates a node for the position in state, reached by
+// the move state.LastMove.
+func newTreeNode(parent *treeNode, state *GameState) *treeNode {
+	node := &treeNode{move: state.LastMove, player: Opponent(state.Player), parent: parent}
+	if state.IsOver() {
+		node.proven = true
+		switch state.Winner() {
+		case EMPTY:
+			node.result = SOLVED_DRAW
+		case PlayerMark(node.player):
+			node.result = SOLVED_WIN
+		default:
+			// Only under the misère or most-boards rules.
+			node.result = SOLVED_LOSS
+		}
+	} else {
+		node.untried = state.ValidMoves()
+	}
+
+	return node
+}
+
+// rank orders children for playing: proven wins first,
+// proven losses last and the most visited in between.
+func (node *treeNode) rank() (int, float64) {
+	if node.proven && node.result != SOLVED_DRAW {
+		return node.result, node.visits
+	}
+
+	return 0, node.visits
+}
+
+// bestChild returns the child with the best rank,
+// or nil if the node has no children.
+func (node *treeNode) bestChild() *treeNode {
+	var bestChild *treeNode
+	for _, child := range node.children {
+		if bestChild == nil || child.ranksAbove(bestChild) {
+			bestChild = child
+		}
+	}
+
+	return bestChild
+}
+
+// ranksAbove returns true if node should be played rather than other.
+func (node *treeNode) ranksAbove(other *treeNode) bool {
+	result, visits := node.rank()
+	otherResult, otherVisits := other.rank()
+	return result > otherResult || (result == otherResult && visits > otherVisits)
+}
+
+// prove checks whether the results of node's children make the result of
+// node certain (MCTS-Solver). One winning move is enough to lose the node,
+// while it is only won (or drawn) once every move has been proven.
+// It returns true if the node is proven.
+func (node *treeNode) prove() bool {
+	if node.proven {
+		return true
+	}
+
+	allProven := len(node.untried) == 0
+	best := SOLVED_LOSS
+	for _, child := range node.children {
+		if !child.proven {
+			allProven = false
+			continue
+		}
+
+		if child.result == SOLVED_WIN {
+			node.proven = true
+			node.result = SOLVED_LOSS
+			return true
+		}
+
+		if child.result > best {
+			best = child.result
+		}
+	}
+
+	if allProven {
+		node.proven = true
+		node.result = -best
+	}
+
+	return node.proven
+}
+
+// size returns the number of nodes in the tree rooted at node.
+func (node *treeNode) size() int {
+	size := 1
+	for _, child := range node.children {
+		size += child.size()
+	}
+
+	return size
+}
+
+// selectChild returns the child with the highest UCT value, skipping
+// children which are proven to lose. With RAVE, the value blends in the
+// child's AMAF statistics, which count most while the child has few visits.
+func (node *treeNode) selectChild(config *SearchConfig) *treeNode {
+	var bestChild *treeNode
+	bestValue := math.Inf(-1)
+	logVisits := math.Log(node.visits)
+
+	for _, child := range node.children {
+		if child.proven && child.result == SOLVED_LOSS {
+			continue
+		}
+
+		value := child.mean()
+		if config.RaveEquivalence > 0 && child.amafVisits > 0 {
+			beta := math.Sqrt(config.RaveEquivalence / (3*child.visits + config.RaveEquivalence))
+			value = (1-beta)*value + beta*child.amafScore/child.amafVisits
+		}
+
+		value += config.Exploration * math.Sqrt(logVisits/child.visits)
+		if value > bestValue {
+			bestValue = value
+			bestChild = child
+		}
+	}
+
+	return bestChild
+}
+
+// SearchTree is a Monte Carlo search tree (UCT) rooted at a position.
+// Unlike MonteCarloBot, which only keeps statistics for the moves it
+// can make right now, the tree keeps growing deeper as the search
+// goes on and can be reused after moves have been made.
+type SearchTree struct {
+	config SearchConfig
+	state  GameState // The position at the root of the tree
+	root   *treeNode
+	played []Move // Reused between playouts to keep track of the moves for RAVE
+
+	// How long has been spent searching the current root.
+	elapsed time.Duration
+}
+
+// NewSearchTree returns a new, empty SearchTree for state.
+func NewSearchTree(state *GameState, config SearchConfig) *SearchTree {
+	return &SearchTree{config: config, state: *state, root: newTreeNode(nil, state)}
+}
+
+// Search grows the tree until the configured ThinkTime or MaxPlayouts
+// is used up, or until stop is closed. A ThinkTime of 0 means the search
+// only stops on MaxPlayouts or stop. It returns the number of playouts made.
+func (tree *SearchTree) Search(stop <-chan struct{}) int {
+	start := time.Now()
+	playouts := 0
+	defer func() { tree.elapsed += time.Since(start) }()
+
+	// Once the result at the root is certain, searching any further is pointless.
+	for !tree.root.proven && (tree.config.MaxPlayouts == 0 || playouts < tree.config.MaxPlayouts) {
+		// Checking the clock is relatively expensive, so only do it every now and then.
+		if playouts%64 == 0 {
+			select {
+			case <-stop:
+				return playouts
+			default:
+			}
+
+			if tree.config.ThinkTime > 0 && time.Since(start) > tree.config.ThinkTime {
+				break
+			}
+		}
+
+		tree.playout()
+		playouts += 1
+	}
+
+	return playouts
+}
+
+// playout runs a single selection, expansion, simulation and
+// backpropagation step on the tree.
+func (tree *SearchTree) playout() {
+	state := tree.state
+	node := tree.root
+
+	// The moves made in this playout, only kept track of for RAVE.
+	var played *[]Move
+	if tree.config.RaveEquivalence > 0 {
+		played = &tree.played
+		tree.played = tree.played[:0]
+	}
+
+	// Select a promising leaf...
+	depth := 0
+	for len(node.untried) == 0 && len(node.children) > 0 && !node.proven {
+		node = node.selectChild(&tree.config)
+		state.Play(&node.move)
+		depth += 1
+		if played != nil {
+			*played = append(*played, node.move)
+		}
+	}
+
+	// ...expand it with a random untried move...
+	if len(node.untried) > 0 && !node.proven {
+		i := rand.Intn(len(node.untried))
+		move := node.untried[i]
+		node.untried[i] = node.untried[len(node.untried)-1]
+		node.untried = node.untried[:len(node.untried)-1]
+
+		state.Play(move)
+		child := newTreeNode(node, &state)
+		node.children = append(node.children, child)
+		node = child
+		depth += 1
+		if played != nil {
+			*played = append(*played, node.move)
+		}
+	}
+
+	// ...play the rest of the game randomly (or let the Evaluator estimate
+	// its result), unless its result is already certain, and update the
+	// statistics on the way back up. Results are counted for player 1,
+	// with a win as 1, a tie as 0.5 and a loss as 0.
+	var winner int
+	var simulatedMoves float64
+	estimated := false
+	switch {
+	case !node.proven && tree.config.Evaluator != nil:
+		estimated = true
+	case !node.proven:
+		winner, simulatedMoves = simulate(&state, tree.config.Policy, played)
+	case node.result == SOLVED_WIN:
+		winner = PlayerMark(node.player)
+	case node.result == SOLVED_LOSS:
+		winner = PlayerMark(Opponent(node.player))
+	default:
+		winner = EMPTY
+	}
+
+	score := 0.5
+	if estimated {
+		score = scoreFor(state.Player, tree.config.Evaluator.Evaluate(&state))
+	} else if winner == PLAYER_1_CONTROLLED {
+		score = 1
+	} else if winner == PLAYER_2_CONTROLLED {
+		score = 0
+	}
+	draw := !estimated && winner == EMPTY
+
+	if played != nil {
+		updateAMAF(node, *played, score)
+	}
+
+	leaf, leafDepth := node, depth
+	for ; node != nil; node, depth = node.parent, depth-1 {
+		// Counting the node's own move, for SELECT_LEGACY.
+		movesUntilGameEnded := float64(leafDepth-depth+1) + simulatedMoves
+
+		node.visits += 1.0
+		if draw {
+			node.draws += 1.0
+			continue
+		}
+
+		nodeScore := scoreFor(node.player, score)
+		node.wins += nodeScore
+		node.weightedWins += nodeScore / movesUntilGameEnded
+		node.weightedLosses += (1 - nodeScore) / movesUntilGameEnded
+	}
+
+	// Pass a proven result on up the tree for as long as it decides the parents.
+	if leaf.proven {
+		for node = leaf.parent; node != nil && node.prove(); node = node.parent {
+		}
+	}
+}
+
+// scoreFor returns score, the result of a playout for player 1,
+// as the result for player.
+func scoreFor(player int, score float64) float64 {
+	if player == 1 {
+		return score
+	}
+
+	return 1 - score
+}
+
+// updateAMAF updates the All-Moves-As-First statistics on the way from leaf
+// back up to the root, where played holds every move made in the playout,
+// starting with the move out of the root, and score its result for player 1.
+// A child's statistics are updated if its move was made, by the same player,
+// anywhere after its parent.
+func updateAMAF(leaf *treeNode, played []Move, score float64) {
+	var seen [3][3][3][3][3]bool // Indexed by player, then the move
+
+	// Moves made during the simulation, below the leaf.
+	depth := 0
+	for node := leaf; node.parent != nil; node = node.parent {
+		depth += 1
+	}
+
+	for i := len(played) - 1; i >= depth; i-- {
+		// Moves alternate, and the last move in the tree was leaf.player's.
+		player := leaf.player
+		if (i-depth)%2 == 0 {
+			player = Opponent(leaf.player)
+		}
+
+		m := played[i]
+		seen[player][m.BoardX][m.BoardY][m.TileX][m.TileY] = true
+	}
+
+	for node, i := leaf, depth-1; node.parent != nil; node, i = node.parent, i-1 {
+		m := played[i]
+		seen[node.player][m.BoardX][m.BoardY][m.TileX][m.TileY] = true
+
+		for _, sibling := range node.parent.children {
+			m := sibling.move
+			if !seen[sibling.player][m.BoardX][m.BoardY][m.TileX][m.TileY] {
+				continue
+			}
+
+			sibling.amafVisits += 1.0
+			sibling.amafScore += scoreFor(sibling.player, score)
+		}
+	}
+}
+
+// BestMove returns the move proven to win at the root, if there is one,
+// and otherwise the best move by the configured SelectionStrategy which
+// is not proven to lose. It returns nil if the search has not visited
+// any moves yet.
+func (tree *SearchTree) BestMove() *Move {
+	candidates := tree.rankCandidates()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[0].Move.Copy()
+}
+
+// rankCandidates returns the moves at the root as CandidateMoves, proven
+// wins first, proven losses last and ranked by the configured
+// SelectionStrategy in between.
+func (tree *SearchTree) rankCandidates() []CandidateMove {
+	children := append([]*treeNode(nil), tree.root.children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		result, _ := children[i].rank()
+		otherResult, _ := children[j].rank()
+		return result > otherResult
+	})
+
+	moves := make([]*Move, len(children))
+	stats := make([]moveStats, len(children))
+	for i, child := range children {
+		moves[i] = &child.move
+		stats[i] = child.moveStats
+	}
+
+	// Rank the moves within each group of equally proven moves.
+	candidates := make([]CandidateMove, 0, len(children))
+	for start := 0; start < len(children); {
+		end := start + 1
+		result, _ := children[start].rank()
+		for end < len(children) {
+			if otherResult, _ := children[end].rank(); otherResult != result {
+				break
+			}
+			end += 1
+		}
+
+		candidates = append(candidates, rankCandidates(moves[start:end], stats[start:end], &tree.config)...)
+		start = end
+	}
+
+	return candidates
+}
+
+// Analysis returns the statistics gathered for the moves at the root,
+// ranked like BestMove ranks them, with the principal variation found by
+// following the proven or most visited moves down the tree.
+func (tree *SearchTree) Analysis() *Analysis {
+	analysis := &Analysis{Playouts: int(tree.root.visits), TimeUsed: tree.elapsed, TreeSize: tree.root.size()}
+	analysis.Candidates = tree.rankCandidates()
+
+	if tree.root.proven {
+		// The result is given from the point of view of the player to move.
+		analysis.Proven = resultName(-tree.root.result)
+	}
+
+	if len(analysis.Candidates) > 0 {
+		analysis.PrincipalVariation = append(analysis.PrincipalVariation, analysis.Candidates[0].Move)
+		for _, child := range tree.root.children {
+			if child.move == analysis.Candidates[0].Move {
+				for node := child.bestChild(); node != nil; node = node.bestChild() {
+					analysis.PrincipalVariation = append(analysis.PrincipalVariation, node.move)
+				}
+			}
+		}
+	}
+
+	return analysis
+}
+
+// Advance moves the root of the tree down to the position reached by
+// playing move, keeping the statistics already gathered below it.
+func (tree *SearchTree) Advance(move *Move) {
+	tree.state.Play(move)
+
+	for _, child := range tree.root.children {
+		if child.move == *move {
+			child.parent = nil
+			tree.root = child
+			tree.elapsed = 0
+			return
+		}
+	}
+
+	// The move was never searched, start over from scratch.
+	tree.root = newTreeNode(nil, &tree.state)
+	tree.elapsed = 0
+}
+
+// TreeSearchBot uses a Monte Carlo search tree (UCT) to find the best
+// move, thinking for DefaultSearchConfig.ThinkTime.
+func TreeSearchBot(playerNumber int, previousMove *Move, board *UltimateBoard) *Move {
+	state := NewGameState(playerNumber, previousMove, board)
+	if state.IsOver() {
+		// HackerRank does not properly detect when a game is already
+		// tied, but will force players to fill up all the boards
+		// before calling it, so we keep playing...
+		return board.AllPossibleMoves()[0]
+	}
+
+	analysis, tree := treeSearch(state, DefaultSearchConfig)
+	if analysis != nil {
+		return analysis.BestMove()
+	}
+
+	return tree.BestMove()
+}
+
+// TreeSearchAnalysis searches state with a new SearchTree and returns the
+// Analysis of the search, unless the opening book knows the position or the
+// endgame solver can settle it.
+func TreeSearchAnalysis(state *GameState, config SearchConfig) *Analysis {
+	analysis, tree := treeSearch(state, config)
+	if analysis == nil {
+		analysis = tree.Analysis()
+	}
+
+	return analysis
+}
+
+// treeSearch returns the Analysis of the opening book or the endgame solver
+// if either settles state, and otherwise nil and a SearchTree which has
+// searched it.
+func treeSearch(state *GameState, config SearchConfig) (*Analysis, *SearchTree) {
+	start := time.Now()
+	if analysis := config.Book.Analysis(state); analysis != nil {
+		slog.Info("TreeSearch played from the opening book", "search", analysis)
+		return analysis, nil
+	}
+
+	if analysis := solveEndgame(state, config.SolverThreshold, config.deadline(start)); analysis != nil {
+		slog.Info("TreeSearch solved the position", "search", analysis)
+		return analysis, nil
+	}
+
+	// The time the solver took is taken off the search's.
+	tree := NewSearchTree(state, config.remaining(start))
+	tree.Search(nil)
+
+	slog.Info("TreeSearch finished", "search", treeAnalysis{tree})
+	return nil, tree
+}
+
+// treeAnalysis logs the Analysis of a SearchTree, which walks the whole
+// tree, only when the log record is actually written.
+type treeAnalysis struct {
+	tree *SearchTree
+}
+
+// LogValue implements slog.LogValuer.
+func (value treeAnalysis) LogValue() slog.Value {
+	return value.tree.Analysis().LogValue()
+}
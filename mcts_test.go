@@ -0,0 +1,211 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var testSearchConfig = SearchConfig{Exploration: DefaultSearchConfig.Exploration, MaxPlayouts: 2000}
+
+func TestSearchTreeFollowsTheRules(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	tree := NewSearchTree(state, testSearchConfig)
+	if playouts := tree.Search(nil); playouts != testSearchConfig.MaxPlayouts {
+		t.Error("Expected the search to stop after", testSearchConfig.MaxPlayouts, "playouts, it made", playouts)
+	}
+
+	move := tree.BestMove()
+	if move.BoardX != 1 || move.BoardY != 1 {
+		t.Error("The search did not stick to the board it was forced to!")
+		t.Error("Instead of (1,1), it played on board (", move.BoardX, ",", move.BoardY, ")")
+	}
+}
+
+func TestSearchTreeFindsWinningMove(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won boards (0,0) and (1,1), and only needs to
+	// win board (2,2) (top left tile missing) to win the game.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+		state.Board[1][1][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][2][2] = PLAYER_1_CONTROLLED
+	state.LastMove = Move{0, 1, 2, 2}
+
+	tree := NewSearchTree(state, testSearchConfig)
+	playouts := tree.Search(nil)
+	if move := tree.BestMove(); *move != (Move{2, 2, 0, 0}) {
+		t.Error("Expected the search to find the winning move (2,2,0,0), it played", *move)
+	}
+
+	// Once the winning move has been tried, the search knows it has won.
+	if tree.Analysis().Proven != "win" || playouts == testSearchConfig.MaxPlayouts {
+		t.Error("Expected the search to prove the win and stop early, it made", playouts, "playouts")
+	}
+}
+
+func TestTreeNodeProve(t *testing.T) {
+	lost := &treeNode{proven: true, result: SOLVED_LOSS}
+	drawn := &treeNode{proven: true, result: SOLVED_DRAW}
+	won := &treeNode{proven: true, result: SOLVED_WIN}
+	unknown := &treeNode{}
+
+	tests := []struct {
+		children []*treeNode
+		untried  []*Move
+		proven   bool
+		result   int
+	}{
+		{[]*treeNode{lost, won}, []*Move{{}}, true, SOLVED_LOSS},
+		{[]*treeNode{lost, drawn}, nil, true, SOLVED_DRAW},
+		{[]*treeNode{lost, lost}, nil, true, SOLVED_WIN},
+		{[]*treeNode{lost, lost}, []*Move{{}}, false, 0},
+		{[]*treeNode{lost, unknown}, nil, false, 0},
+	}
+
+	for i, test := range tests {
+		node := &treeNode{children: test.children, untried: test.untried}
+		if node.prove() != test.proven || node.result != test.result {
+			t.Errorf("Test %d: expected proven %v with result %d, got %v with result %d", i, test.proven, test.result, node.proven, node.result)
+		}
+	}
+}
+
+func TestSearchTreeSolvesEndgames(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	config := SearchConfig{Exploration: DefaultSearchConfig.Exploration, MaxPlayouts: 50000}
+
+	for i := 0; i < 10; i++ {
+		state := randomEndgame(random, 8)
+		if state.IsOver() {
+			continue
+		}
+
+		tree := NewSearchTree(state, config)
+		tree.Search(nil)
+		if !tree.root.proven {
+			t.Error("Expected the search to prove a position with 8 empty squares")
+			continue
+		}
+
+		if expected := resultName(minimax(state)); tree.Analysis().Proven != expected {
+			t.Error("The search proved a", tree.Analysis().Proven, "but minimax says", expected)
+		}
+
+		next := *state
+		next.Play(tree.BestMove())
+		if -minimax(&next) != minimax(state) {
+			t.Error("The search's best move", *tree.BestMove(), "does not achieve the proven result")
+		}
+	}
+}
+
+func TestSearchTreeAdvance(t *testing.T) {
+	tree := NewSearchTree(NewGame(), testSearchConfig)
+	tree.Search(nil)
+
+	move := tree.BestMove()
+	visits := tree.root.children[0].visits
+	for _, child := range tree.root.children {
+		if child.move == *move {
+			visits = child.visits
+		}
+	}
+
+	tree.Advance(move)
+	if tree.root.visits != visits || tree.root.parent != nil {
+		t.Error("Advance() did not keep the statistics below the move that was played!")
+	}
+
+	if tree.state.Player != 2 || tree.state.LastMove != *move {
+		t.Error("Advance() did not play the move on the tree's position!")
+	}
+}
+
+func TestSearchTreeAnalysis(t *testing.T) {
+	tree := NewSearchTree(NewGame(), testSearchConfig)
+	tree.Search(nil)
+
+	analysis := tree.Analysis()
+	if analysis.Playouts != testSearchConfig.MaxPlayouts || len(analysis.Candidates) != 81 {
+		t.Error("Expected", testSearchConfig.MaxPlayouts, "playouts over 81 moves, got", analysis.Playouts, "over", len(analysis.Candidates))
+	}
+
+	if *analysis.BestMove() != *tree.BestMove() || analysis.PrincipalVariation[0] != *tree.BestMove() {
+		t.Error("The analysis should rank the move the search would play first!")
+	}
+
+	// The moves in the principal variation should follow the forced board rule.
+	state := NewGame()
+	for _, move := range analysis.PrincipalVariation {
+		legal := false
+		for _, validMove := range state.ValidMoves() {
+			legal = legal || *validMove == move
+		}
+
+		if !legal {
+			t.Fatal("The principal variation contains an illegal move", move)
+		}
+		state.Play(&move)
+	}
+}
+
+func TestSearchTreeRave(t *testing.T) {
+	config := testSearchConfig
+	config.RaveEquivalence = DEFAULT_RAVE_EQUIVALENCE
+
+	tree := NewSearchTree(NewGame(), config)
+	tree.Search(nil)
+
+	// Every visit of a move also counts as a visit "as first",
+	// and most playouts make plenty of other moves too.
+	var visits, amafVisits float64
+	for _, child := range tree.root.children {
+		if child.amafVisits < child.visits {
+			t.Fatal("A move had fewer AMAF visits than visits:", child.amafVisits, "<", child.visits)
+		}
+
+		visits += child.visits
+		amafVisits += child.amafVisits
+	}
+
+	if amafVisits < 5*visits {
+		t.Error("Expected many more AMAF visits than visits, got", amafVisits, "and", visits)
+	}
+}
+
+func TestSearchTreeEvaluator(t *testing.T) {
+	config := testSearchConfig
+	config.Evaluator = DefaultEvaluator
+
+	tree := NewSearchTree(NewGame(), config)
+	tree.Search(nil)
+
+	// Without simulated games no playout ends in a tie.
+	for _, child := range tree.root.children {
+		if child.draws != 0 || child.wins <= 0 || child.wins >= child.visits {
+			t.Fatal("Expected estimated results strictly between a loss and a win, got", child.wins, "wins in", child.visits, "visits")
+		}
+	}
+}
+
+func TestSearchConfigRemaining(t *testing.T) {
+	config := SearchConfig{ThinkTime: time.Second}
+	start := time.Now().Add(-300 * time.Millisecond)
+	if remaining := config.remaining(start).ThinkTime; remaining > 700*time.Millisecond || remaining < 600*time.Millisecond {
+		t.Error("Expected about 700ms left to think, got", remaining)
+	}
+
+	if remaining := config.remaining(start.Add(-time.Hour)).ThinkTime; remaining != time.Nanosecond {
+		t.Error("Expected the search to still be limited once out of time, got", remaining)
+	}
+
+	if unlimited := (SearchConfig{}).remaining(start); unlimited.ThinkTime != 0 || !unlimited.deadline(start).IsZero() {
+		t.Error("Expected a search without a ThinkTime to stay unlimited")
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const (
+	NETWORK_INPUTS = 2*81 + 9 + 1 // The player to move's squares, the opponent's, the boards open to play and who moves
+	NETWORK_MOVES  = 81           // One policy output for every square, see moveIndex
+)
+
+// DefaultHiddenLayers are the sizes of the hidden layers of a new Network.
+var DefaultHiddenLayers = []int{128, 64}
+
+// NETWORK_LEARNING_RATE is the learning rate the network is trained with.
+const NETWORK_LEARNING_RATE = 0.005
+
+// NETWORK_MAGIC starts every network weights file, followed by its version.
+const (
+	NETWORK_MAGIC   = "UTNN"
+	NETWORK_VERSION = 1
+)
+
+// A ValuePolicy estimates how good a position is for the player to move, from
+// -1 (lost) to 1 (won), and how likely each of the moves is to be the best.
+type ValuePolicy interface {
+	Predict(state *GameState, moves []*Move) (float64, []float64)
+}
+
+// denseLayer is a fully connected layer, computing Weights * input + Biases.
+type denseLayer struct {
+	Inputs  int
+	Outputs int
+	Weights []float64 // Outputs rows of Inputs weights
+	Biases  []float64
+}
+
+// newDenseLayer returns a layer with random weights, scaled
+// to keep the size of the activations steady (He initialization).
+func newDenseLayer(inputs, outputs int, random *rand.Rand) *denseLayer {
+	layer := &denseLayer{Inputs: inputs, Outputs: outputs, Weights: make([]float64, inputs*outputs), Biases: make([]float64, outputs)}
+	scale := math.Sqrt(2 / float64(inputs))
+	for i := range layer.Weights {
+		layer.Weights[i] = random.NormFloat64() * scale
+	}
+
+	return layer
+}
+
+// forward writes the layer's output for input to output.
+func (layer *denseLayer) forward(input, output []float64) {
+	for out := 0; out < layer.Outputs; out++ {
+		sum := layer.Biases[out]
+		row := layer.Weights[out*layer.Inputs : (out+1)*layer.Inputs]
+		for in, weight := range row {
+			sum += weight * input[in]
+		}
+		output[out] = sum
+	}
+}
+
+// backward takes a gradient step of learningRate, given the input the layer
+// was run on and the gradient of the loss with respect to its output. If
+// inputGradient is not nil, the gradient with respect to the input is added to it.
+func (layer *denseLayer) backward(input, outputGradient, inputGradient []float64, learningRate float64) {
+	for out := 0; out < layer.Outputs; out++ {
+		gradient := outputGradient[out]
+		if gradient == 0 {
+			continue
+		}
+
+		row := layer.Weights[out*layer.Inputs : (out+1)*layer.Inputs]
+		for in := range row {
+			if inputGradient != nil {
+				inputGradient[in] += row[in] * gradient
+			}
+			row[in] -= learningRate * gradient * input[in]
+		}
+		layer.Biases[out] -= learningRate * gradient
+	}
+}
+
+// Network is a small neural network with a few hidden layers of rectified
+// linear units, shared by a value head (a single tanh output) and a policy
+// head (a softmax over the legal moves).
+type Network struct {
+	hidden []*denseLayer
+	value  *denseLayer
+	policy *denseLayer
+}
+
+// NewNetwork returns a Network with random weights and hidden layers of the given sizes.
+func NewNetwork(hiddenLayers []int, random *rand.Rand) *Network {
+	network := &Network{}
+	inputs := NETWORK_INPUTS
+	for _, size := range hiddenLayers {
+		network.hidden = append(network.hidden, newDenseLayer(inputs, size, random))
+		inputs = size
+	}
+
+	network.value = newDenseLayer(inputs, 1, random)
+	network.policy = newDenseLayer(inputs, NETWORK_MOVES, random)
+	return network
+}
+
+// moveIndex returns the policy output of move.
+func moveIndex(move *Move) int {
+	return move.BoardX*27 + move.BoardY*9 + move.TileX*3 + move.TileY
+}
+
+// encodeState returns the network's input for state, from the point
+// of view of the player to move.
+func encodeState(state *GameState) []float64 {
+	input := make([]float64, NETWORK_INPUTS)
+	mark := PlayerMark(state.Player)
+	for i := 0; i < NETWORK_MOVES; i++ {
+		switch square := state.Board[i/27][i/9%3][i/3%3][i%3]; square {
+		case EMPTY:
+		case mark:
+			input[i] = 1
+		default:
+			input[NETWORK_MOVES+i] = 1
+		}
+	}
+
+	if x, y, forced := state.ForcedBoard(); forced {
+		input[2*NETWORK_MOVES+x*3+y] = 1
+	} else {
+		for i := 0; i < 9; i++ {
+			if state.Board[i/3][i%3].HasWinner() == EMPTY && hasEmptyTile(&state.Board[i/3][i%3], -1, -1) {
+				input[2*NETWORK_MOVES+i] = 1
+			}
+		}
+	}
+
+	if state.Player == 1 {
+		input[NETWORK_INPUTS-1] = 1
+	}
+
+	return input
+}
+
+// activations holds the outputs of every layer for one position.
+type activations struct {
+	layers [][]float64 // The input, then the output of every hidden layer after the ReLU
+	value  float64
+	priors []float64 // Indexed like moves
+}
+
+// forward runs the network on input, with the policy
+// restricted to the moves with the given indices.
+func (network *Network) forward(input []float64, moves []int) *activations {
+	result := &activations{layers: [][]float64{input}}
+	for _, layer := range network.hidden {
+		output := make([]float64, layer.Outputs)
+		layer.forward(input, output)
+		for i := range output {
+			output[i] = math.Max(output[i], 0)
+		}
+		result.layers = append(result.layers, output)
+		input = output
+	}
+
+	var value [1]float64
+	network.value.forward(input, value[:])
+	result.value = math.Tanh(value[0])
+
+	logits := make([]float64, NETWORK_MOVES)
+	network.policy.forward(input, logits)
+	result.priors = make([]float64, len(moves))
+	maxLogit := math.Inf(-1)
+	for _, move := range moves {
+		maxLogit = math.Max(maxLogit, logits[move])
+	}
+
+	var sum float64
+	for i, move := range moves {
+		result.priors[i] = math.Exp(logits[move] - maxLogit)
+		sum += result.priors[i]
+	}
+	for i := range result.priors {
+		result.priors[i] /= sum
+	}
+
+	return result
+}
+
+// Predict implements ValuePolicy.
+func (network *Network) Predict(state *GameState, moves []*Move) (float64, []float64) {
+	indices := make([]int, len(moves))
+	for i, move := range moves {
+		indices[i] = moveIndex(move)
+	}
+
+	result := network.forward(encodeState(state), indices)
+	return result.value, result.priors
+}
+
+// trainSample takes a gradient step of learningRate on the squared error of
+// the value plus the cross-entropy of the priors for sample, and returns both.
+func (network *Network) trainSample(sample *TrainingSample, learningRate float64) (float64, float64) {
+	moves, targets := sample.moves()
+	result := network.forward(encodeState(&sample.State), moves)
+
+	valueError := result.value - sample.Value
+	valueGradient := []float64{2 * valueError * (1 - result.value*result.value)}
+
+	var policyLoss float64
+	policyGradient := make([]float64, NETWORK_MOVES)
+	for i, move := range moves {
+		policyGradient[move] = result.priors[i] - targets[i]
+		if targets[i] > 0 {
+			policyLoss -= targets[i] * math.Log(math.Max(result.priors[i], 1e-12))
+		}
+	}
+
+	last := result.layers[len(result.layers)-1]
+	gradient := make([]float64, len(last))
+	network.value.backward(last, valueGradient, gradient, learningRate)
+	network.policy.backward(last, policyGradient, gradient, learningRate)
+
+	for i := len(network.hidden) - 1; i >= 0; i-- {
+		output := result.layers[i+1]
+		for j := range gradient {
+			if output[j] <= 0 {
+				// The ReLU was off, nothing flows back through it.
+				gradient[j] = 0
+			}
+		}
+
+		var inputGradient []float64
+		if i > 0 {
+			inputGradient = make([]float64, len(result.layers[i]))
+		}
+		network.hidden[i].backward(result.layers[i], gradient, inputGradient, learningRate)
+		gradient = inputGradient
+	}
+
+	return valueError * valueError, policyLoss
+}
+
+// Train fits the network to samples with epochs passes of stochastic
+// gradient descent, and returns the mean value and policy loss of the last pass.
+func (network *Network) Train(samples []TrainingSample, epochs int, learningRate float64, random *rand.Rand) (float64, float64) {
+	var valueLoss, policyLoss float64
+	order := random.Perm(len(samples))
+	for epoch := 0; epoch < epochs; epoch++ {
+		random.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+
+		valueLoss, policyLoss = 0, 0
+		for _, i := range order {
+			sampleValueLoss, samplePolicyLoss := network.trainSample(&samples[i], learningRate)
+			valueLoss += sampleValueLoss
+			policyLoss += samplePolicyLoss
+		}
+
+		valueLoss /= float64(len(samples))
+		policyLoss /= float64(len(samples))
+	}
+
+	return valueLoss, policyLoss
+}
+
+// layers returns every layer of the network, in the order they are saved.
+func (network *Network) layers() []*denseLayer {
+	return append(append([]*denseLayer(nil), network.hidden...), network.value, network.policy)
+}
+
+// Save writes the network to w: NETWORK_MAGIC, then the version, the number
+// of hidden layers and for every layer (hidden layers, the value head and the
+// policy head) its number of inputs and outputs, its weights and its biases.
+// Everything is little-endian, with 32-bit integers and 32-bit floats.
+func (network *Network) Save(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	buffer.WriteString(NETWORK_MAGIC)
+	header := []uint32{NETWORK_VERSION, uint32(len(network.hidden))}
+	if err := binary.Write(buffer, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	for _, layer := range network.layers() {
+		if err := binary.Write(buffer, binary.LittleEndian, []uint32{uint32(layer.Inputs), uint32(layer.Outputs)}); err != nil {
+			return err
+		}
+
+		for _, values := range [][]float64{layer.Weights, layer.Biases} {
+			floats := make([]float32, len(values))
+			for i, value := range values {
+				floats[i] = float32(value)
+			}
+			if err := binary.Write(buffer, binary.LittleEndian, floats); err != nil {
+				return err
+			}
+		}
+	}
+
+	return buffer.Flush()
+}
+
+// ReadNetwork reads a network written by Save.
+func ReadNetwork(r io.Reader) (*Network, error) {
+	reader := bufio.NewReader(r)
+	magic := make([]byte, len(NETWORK_MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != NETWORK_MAGIC {
+		return nil, fmt.Errorf("not a network weights file")
+	}
+
+	var header [2]uint32
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header[0] != NETWORK_VERSION {
+		return nil, fmt.Errorf("expected version %d network weights, got version %d", NETWORK_VERSION, header[0])
+	}
+
+	network := &Network{}
+	inputs := NETWORK_INPUTS
+	for i := 0; i < int(header[1])+2; i++ {
+		var size [2]uint32
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+
+		outputs := int(size[1])
+		switch {
+		case i == int(header[1]):
+			outputs = 1
+		case i == int(header[1])+1:
+			outputs = NETWORK_MOVES
+		}
+		if int(size[0]) != inputs || int(size[1]) != outputs || outputs > 1<<16 {
+			return nil, fmt.Errorf("layer %d has %d inputs and %d outputs, expected %d inputs and %d outputs", i, size[0], size[1], inputs, outputs)
+		}
+
+		layer := &denseLayer{Inputs: inputs, Outputs: outputs}
+		weights := make([]float32, inputs*outputs)
+		biases := make([]float32, outputs)
+		if err := binary.Read(reader, binary.LittleEndian, weights); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(reader, binary.LittleEndian, biases); err != nil {
+			return nil, err
+		}
+
+		for _, weight := range weights {
+			layer.Weights = append(layer.Weights, float64(weight))
+		}
+		for _, bias := range biases {
+			layer.Biases = append(layer.Biases, float64(bias))
+		}
+
+		switch {
+		case i < int(header[1]):
+			network.hidden = append(network.hidden, layer)
+			inputs = outputs
+		case i == int(header[1]):
+			network.value = layer
+		default:
+			network.policy = layer
+		}
+	}
+
+	return network, nil
+}
+
+// LoadNetwork reads the network weights file at path.
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadNetwork(file)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNetworkPredict(t *testing.T) {
+	network := NewNetwork(DefaultHiddenLayers, rand.New(rand.NewSource(1)))
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	moves := state.ValidMoves()
+	value, priors := network.Predict(state, moves)
+	if value <= -1 || value >= 1 {
+		t.Error("Expected a value between -1 and 1, got", value)
+	}
+
+	var sum float64
+	for _, prior := range priors {
+		sum += prior
+	}
+	if len(priors) != len(moves) || math.Abs(sum-1) > 1e-9 {
+		t.Error("Expected a prior for each of the", len(moves), "moves, summing to 1, got", priors)
+	}
+}
+
+func TestEncodeState(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+	state.Play(&Move{1, 1, 2, 0})
+
+	input := encodeState(state)
+	if input[moveIndex(&Move{0, 0, 1, 1})] != 1 || input[NETWORK_MOVES+moveIndex(&Move{1, 1, 2, 0})] != 1 {
+		t.Error("Expected player 1's square in the first plane and player 2's in the second")
+	}
+
+	if input[2*NETWORK_MOVES+2*3+0] != 1 || input[NETWORK_INPUTS-1] != 1 {
+		t.Error("Expected player 1 to move, sent to board (2,0)")
+	}
+
+	var ones float64
+	for _, value := range input {
+		ones += value
+	}
+	if ones != 4 {
+		t.Error("Expected 4 inputs to be set, got", ones)
+	}
+}
+
+func TestNetworkSaveAndRead(t *testing.T) {
+	network := NewNetwork([]int{16}, rand.New(rand.NewSource(1)))
+
+	var buffer bytes.Buffer
+	if err := network.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadNetwork(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal("Failed to read the saved network:", err)
+	}
+
+	state := NewGame()
+	moves := state.ValidMoves()
+	value, priors := network.Predict(state, moves)
+	readValue, readPriors := read.Predict(state, moves)
+	if math.Abs(value-readValue) > 1e-5 || math.Abs(priors[0]-readPriors[0]) > 1e-5 {
+		t.Error("The read network predicts", readValue, readPriors[0], "instead of", value, priors[0])
+	}
+
+	if _, err := ReadNetwork(bytes.NewReader(buffer.Bytes()[:100])); err == nil {
+		t.Error("Expected an error reading a truncated network")
+	}
+
+	if _, err := ReadNetwork(bytes.NewReader([]byte("version 1\n"))); err == nil {
+		t.Error("Expected an error reading a file that is not a network")
+	}
+}
+
+func TestNetworkTrain(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	// In every position, the move to the center of the forced board wins.
+	var samples []TrainingSample
+	for i := 0; i < 9; i++ {
+		state := NewGame()
+		state.Play(&Move{1, 1, i / 3, i % 3})
+
+		sample := TrainingSample{State: *state, Value: 1}
+		sample.Policy[moveIndex(&Move{i / 3, i % 3, 1, 1})] = 1
+		samples = append(samples, sample)
+	}
+
+	network := NewNetwork([]int{32}, random)
+	network.Train(samples, 1, NETWORK_LEARNING_RATE, random)
+	firstValueLoss, firstPolicyLoss := network.Train(samples, 1, NETWORK_LEARNING_RATE, random)
+	valueLoss, policyLoss := network.Train(samples, 200, NETWORK_LEARNING_RATE, random)
+	if valueLoss >= firstValueLoss/10 || policyLoss >= firstPolicyLoss/10 {
+		t.Error("Expected training to fit the samples, the losses went from", firstValueLoss, firstPolicyLoss, "to", valueLoss, policyLoss)
+	}
+
+	state := samples[4].State
+	moves := state.ValidMoves()
+	_, priors := network.Predict(&state, moves)
+	for i, move := range moves {
+		if *move == (Move{1, 1, 1, 1}) && priors[i] < 0.5 {
+			t.Error("Expected the network to learn the winning move, it gives it a prior of", priors[i])
+		}
+	}
+}
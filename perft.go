@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Perft counts the positions reached after exactly depth moves from
+// state. Games that end sooner do not count, which makes the numbers
+// a fingerprint of the move generation (including the forced board
+// rule and when a game counts as over).
+func Perft(state *GameState, depth int) uint64 {
+	return perft(NewGameHistory(state), depth)
+}
+
+// perft counts the positions for Perft, making and taking back the moves
+// on history instead of copying the board.
+func perft(history *GameHistory, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	if history.IsOver() {
+		return 0
+	}
+
+	moves := history.ValidMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, move := range moves {
+		history.Make(move)
+		nodes += perft(history, depth-1)
+		history.Unmake()
+	}
+
+	return nodes
+}
+
+// PerftDivide returns the Perft count below each of the moves that can
+// be made in state, formatted one move per line followed by the total.
+// Comparing the per-move counts narrows a mismatch down to a single move.
+func PerftDivide(state *GameState, depth int) string {
+	var buffer bytes.Buffer
+	var total uint64
+
+	if depth > 0 && !state.IsOver() {
+		history := NewGameHistory(state)
+		for _, move := range state.ValidMoves() {
+			history.Make(move)
+			nodes := perft(history, depth-1)
+			history.Unmake()
+			total += nodes
+
+			fmt.Fprintf(&buffer, "%d %d %d %d: %d\n", move.BoardX, move.BoardY, move.TileX, move.TileY, nodes)
+		}
+	} else {
+		total = Perft(state, depth)
+	}
+
+	fmt.Fprintf(&buffer, "total: %d\n", total)
+	return buffer.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// perftPositions are board states in HackerRank's format, with their
+// reference Perft counts for depth 0, 1, 2...
+var perftPositions = []struct {
+	name     string
+	position string
+	counts   []uint64
+}{
+	{
+		"empty board",
+		"X\n-1 -1\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n",
+		[]uint64{1, 81, 720, 6336, 55080},
+	},
+	{
+		"second move",
+		"O\n0 2\n--------X\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n",
+		[]uint64{1, 8, 72, 624, 5376},
+	},
+	{
+		// Boards (0,0) and (1,1) are won by X, (0,1) is full and
+		// O is forced to (2,2) where X threatens to win the game.
+		"won and full boards",
+		"O\n2 2\nX--XOXO--\n-X-XOO---\n--XOXX---\n---XXX-O-\n-O-OO----\n---------\n---O--X--\n-------X-\nO-O------\n",
+		[]uint64{1, 7, 90, 1506, 24227},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, test := range perftPositions {
+		state, err := ReadGameState(strings.NewReader(test.position))
+		if err != nil {
+			t.Fatal("Failed to read the", test.name, "position:", err)
+		}
+
+		for depth, expected := range test.counts {
+			if nodes := Perft(state, depth); nodes != expected {
+				t.Errorf("%s: Perft(%d) = %d, expected %d", test.name, depth, nodes, expected)
+			}
+		}
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	state, _ := ReadGameState(strings.NewReader(perftPositions[2].position))
+
+	output := PerftDivide(state, 2)
+	if !strings.HasSuffix(output, "total: 90\n") || strings.Count(output, "\n") != 8 {
+		t.Error("Expected 7 moves and a total of 90, got:\n" + output)
+	}
+}
+
+func TestRandomBotPlaysValidMoves(t *testing.T) {
+	// Play a few random games, checking every move against the move generation.
+	for game := 0; game < 50; game++ {
+		state := NewGame()
+		for !state.IsOver() {
+			move := RandomBot(&state.LastMove, &state.Board)
+
+			valid := false
+			for _, validMove := range state.ValidMoves() {
+				valid = valid || *validMove == *move
+			}
+
+			if !valid {
+				t.Fatal("RandomBot made an invalid move", *move, "after", state.LastMove)
+			}
+			state.Play(move)
+		}
+	}
+}
+
+func BenchmarkPerftEmptyBoard(b *testing.B) {
+	state := NewGame()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Perft(state, 3)
+	}
+}
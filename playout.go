@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// DEFAULT_EPSILON is how often the "heavy" bot's playouts make a uniformly
+// random move instead of following HeavyPolicy, to keep some variety.
+const DEFAULT_EPSILON = 0.1
+
+// A PlayoutPolicy picks the moves made while simulating the rest of a game.
+// ChooseMove is given the position and the (non-empty) moves that can be made.
+type PlayoutPolicy interface {
+	ChooseMove(state *GameState, moves []*Move) *Move
+}
+
+// randomIntn returns random.Intn(n), or rand.Intn(n) if random is nil.
+// The policies take an optional source of randomness this way, so that
+// their moves can be repeated, while they are safe for concurrent use
+// without one.
+func randomIntn(random *rand.Rand, n int) int {
+	if random == nil {
+		return rand.Intn(n)
+	}
+	return random.Intn(n)
+}
+
+// randomFloat64 returns random.Float64(), or rand.Float64() if random is nil.
+func randomFloat64(random *rand.Rand) float64 {
+	if random == nil {
+		return rand.Float64()
+	}
+	return random.Float64()
+}
+
+// UniformPolicy picks any of the moves with equal probability, like RandomBot.
+type UniformPolicy struct {
+	Random *rand.Rand // The source of randomness (nil means math/rand's)
+}
+
+// ChooseMove implements PlayoutPolicy.
+func (policy UniformPolicy) ChooseMove(state *GameState, moves []*Move) *Move {
+	return moves[randomIntn(policy.Random, len(moves))]
+}
+
+// HeavyPolicy plays like a cautious beginner. It wins the game if it can,
+// otherwise it wins a board or blocks the opponent from winning one, while
+// avoiding moves that send the opponent somewhere they can win the game.
+// Ties are broken at random. Under the misère or most-boards rules, where
+// lines of boards do not win, it plays like UniformPolicy.
+type HeavyPolicy struct {
+	Random *rand.Rand // The source of randomness (nil means math/rand's)
+}
+
+// ChooseMove implements PlayoutPolicy.
+func (policy HeavyPolicy) ChooseMove(state *GameState, moves []*Move) *Move {
+	if state.Rules&(RULES_MISERE|RULES_MOST_BOARDS) != 0 {
+		return UniformPolicy{policy.Random}.ChooseMove(state, moves)
+	}
+
+	mark := PlayerMark(state.Player)
+	opponentMark := PlayerMark(Opponent(state.Player))
+
+	// Who has won which board, as a board of its own.
+	var macro TictactoeBoard
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			macro[i][j] = state.Board[i][j].HasWinner()
+		}
+	}
+
+	var boardWins, blocks, safeMoves []*Move
+	for _, move := range moves {
+		board := &state.Board[move.BoardX][move.BoardY]
+		winsBoard := completesLine(board, move.TileX, move.TileY, mark)
+		if winsBoard && completesLine(&macro, move.BoardX, move.BoardY, mark) {
+			return move
+		}
+
+		// Look for the opponent's winning replies once the move is made.
+		next := state.Board
+		next[move.BoardX][move.BoardY][move.TileX][move.TileY] = mark
+		nextMacro := macro
+		if winsBoard {
+			nextMacro[move.BoardX][move.BoardY] = mark
+		}
+		if canWinGame(&next, &nextMacro, move, opponentMark, state.Rules&RULES_FREE_MOVES != 0) {
+			continue
+		}
+
+		safeMoves = append(safeMoves, move)
+		if winsBoard {
+			boardWins = append(boardWins, move)
+		} else if completesLine(board, move.TileX, move.TileY, opponentMark) {
+			blocks = append(blocks, move)
+		}
+	}
+
+	for _, candidates := range [][]*Move{boardWins, blocks, safeMoves, moves} {
+		if len(candidates) > 0 {
+			return candidates[randomIntn(policy.Random, len(candidates))]
+		}
+	}
+
+	return nil
+}
+
+// EpsilonGreedyPolicy follows Greedy, except for a share of Epsilon
+// of the moves, which are picked uniformly at random.
+type EpsilonGreedyPolicy struct {
+	Epsilon float64
+	Greedy  PlayoutPolicy
+	Random  *rand.Rand // The source of randomness (nil means math/rand's)
+}
+
+// ChooseMove implements PlayoutPolicy.
+func (policy EpsilonGreedyPolicy) ChooseMove(state *GameState, moves []*Move) *Move {
+	if randomFloat64(policy.Random) < policy.Epsilon {
+		return moves[randomIntn(policy.Random, len(moves))]
+	}
+
+	return policy.Greedy.ChooseMove(state, moves)
+}
+
+// NewPlayoutPolicy returns the policy called name ("uniform" or "heavy").
+// A heavy policy makes a share of epsilon of its moves at random.
+func NewPlayoutPolicy(name string, epsilon float64) (PlayoutPolicy, error) {
+	switch name {
+	case "uniform":
+		return UniformPolicy{}, nil
+	case "heavy":
+		if epsilon > 0 {
+			return EpsilonGreedyPolicy{Epsilon: epsilon, Greedy: HeavyPolicy{}}, nil
+		}
+		return HeavyPolicy{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown playout policy %q, expected uniform or heavy", name)
+}
+
+// completesLine returns true if placing mark on tile (x, y)
+// of board would complete a line of three.
+func completesLine(board *TictactoeBoard, x, y, mark int) bool {
+	if board[x][(y+1)%3] == mark && board[x][(y+2)%3] == mark {
+		return true
+	}
+
+	if board[(x+1)%3][y] == mark && board[(x+2)%3][y] == mark {
+		return true
+	}
+
+	if x == y && board[(x+1)%3][(y+1)%3] == mark && board[(x+2)%3][(y+2)%3] == mark {
+		return true
+	}
+
+	return x+y == 2 && board[(x+1)%3][(y+2)%3] == mark && board[(x+2)%3][(y+1)%3] == mark
+}
+
+// canWinGame returns true if mark can win the game with its next move
+// after lastMove, on board with the boards won as in macro. Unless free,
+// mark has to play on the board lastMove sends it to while that board
+// is neither won nor full.
+func canWinGame(board *UltimateBoard, macro *TictactoeBoard, lastMove *Move, mark int, free bool) bool {
+	x, y := lastMove.TileX, lastMove.TileY
+	forced := !free && macro[x][y] == EMPTY && hasEmptyTile(&board[x][y], -1, -1)
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if (forced && (i != x || j != y)) || macro[i][j] != EMPTY {
+				continue
+			}
+
+			if completesLine(macro, i, j, mark) && canCompleteLine(&board[i][j], mark) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// canCompleteLine returns true if mark can complete a
+// line of three on board with a single move.
+func canCompleteLine(board *TictactoeBoard, mark int) bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if board[i][j] == EMPTY && completesLine(board, i, j, mark) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasEmptyTile returns true if board has an empty tile other than (x, y).
+func hasEmptyTile(board *TictactoeBoard, x, y int) bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if board[i][j] == EMPTY && (i != x || j != y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// simulate plays out the rest of the game from state, picking the moves with
+// policy (UniformPolicy if nil), and returns the winner (EMPTY for a tie) and
+// the number of moves made. If played is not nil, the moves are appended to it.
+// The status of the board is updated move by move rather than checked again.
+func simulate(state *GameState, policy PlayoutPolicy, played *[]Move) (int, float64) {
+	if policy == nil {
+		policy = UniformPolicy{}
+	}
+
+	status := NewBoardStatus(&state.Board)
+	var moves float64
+	for state.Rules.StatusWinner(&status) == EMPTY {
+		validMoves := status.ValidMoves(state)
+		if len(validMoves) == 0 {
+			return EMPTY, moves
+		}
+
+		moves += 1.0
+		move := policy.ChooseMove(state, validMoves)
+		state.Play(move)
+		status.Update(&state.Board, move)
+		if played != nil {
+			*played = append(*played, *move)
+		}
+	}
+
+	return state.Rules.StatusWinner(&status), moves
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCompletesLine(t *testing.T) {
+	var board TictactoeBoard
+	board.Clear()
+	board[0][0] = PLAYER_1_CONTROLLED
+	board[1][1] = PLAYER_1_CONTROLLED
+	board[0][2] = PLAYER_2_CONTROLLED
+	board[1][2] = PLAYER_2_CONTROLLED
+
+	tests := []struct {
+		x, y, mark int
+		expected   bool
+	}{
+		{2, 2, PLAYER_1_CONTROLLED, true},  // Diagonal
+		{2, 2, PLAYER_2_CONTROLLED, true},  // Column
+		{2, 0, PLAYER_1_CONTROLLED, false}, // Blocked by the O in the corner
+		{0, 1, PLAYER_1_CONTROLLED, false},
+		{2, 1, PLAYER_2_CONTROLLED, false},
+	}
+
+	for _, test := range tests {
+		if completesLine(&board, test.x, test.y, test.mark) != test.expected {
+			t.Errorf("completesLine(%d, %d, %c) should be %v", test.x, test.y, test.mark, test.expected)
+		}
+	}
+}
+
+func TestHeavyPolicyWinsTheGame(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won boards (0,0) and (1,1), and can win board (2,2),
+	// and thereby the game, or board (2,1), which is worth less.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+		state.Board[1][1][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][2][2] = PLAYER_1_CONTROLLED
+	state.Board[2][1][0][1] = PLAYER_1_CONTROLLED
+	state.Board[2][1][0][2] = PLAYER_1_CONTROLLED
+
+	for i := 0; i < 20; i++ {
+		if move := (HeavyPolicy{}).ChooseMove(state, state.ValidMoves()); *move != (Move{2, 2, 0, 0}) {
+			t.Fatal("Expected the heavy policy to win the game with (2,2,0,0), it played", *move)
+		}
+	}
+}
+
+func TestHeavyPolicyBlocks(t *testing.T) {
+	state := NewGame()
+	state.Board[1][1][0][0] = PLAYER_2_CONTROLLED
+	state.Board[1][1][0][1] = PLAYER_2_CONTROLLED
+	state.LastMove = Move{0, 0, 1, 1}
+
+	for i := 0; i < 20; i++ {
+		if move := (HeavyPolicy{}).ChooseMove(state, state.ValidMoves()); *move != (Move{1, 1, 0, 2}) {
+			t.Fatal("Expected the heavy policy to block player 2 with (1,1,0,2), it played", *move)
+		}
+	}
+}
+
+func TestHeavyPolicyAvoidsLosingTheGame(t *testing.T) {
+	state := NewGame()
+
+	// Player 2 has won boards (0,0) and (1,1), and can win board (2,2),
+	// and thereby the game, if player 1 sends them there.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_2_CONTROLLED
+		state.Board[1][1][i][i] = PLAYER_2_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_2_CONTROLLED
+	state.Board[2][2][2][2] = PLAYER_2_CONTROLLED
+	state.LastMove = Move{0, 0, 0, 1}
+
+	for i := 0; i < 20; i++ {
+		move := (HeavyPolicy{}).ChooseMove(state, state.ValidMoves())
+		if move.TileX == 2 && move.TileY == 2 {
+			t.Fatal("The heavy policy sent player 2 to the board where they win the game with", *move)
+		}
+	}
+}
+
+func TestHeavyPolicyLooksAfterTheMove(t *testing.T) {
+	// losesGame returns true if the opponent can win the game right after move.
+	losesGame := func(state *GameState, move *Move) bool {
+		next := *state
+		next.Play(move)
+		if next.IsOver() {
+			return false
+		}
+
+		for _, reply := range next.ValidMoves() {
+			after := next
+			after.Play(reply)
+			if after.Winner() == PlayerMark(next.Player) {
+				return true
+			}
+		}
+		return false
+	}
+
+	random := rand.New(rand.NewSource(3))
+	for game := 0; game < 100; game++ {
+		state := NewGame()
+		for !state.IsOver() {
+			moves := state.ValidMoves()
+			safe := false
+			for _, move := range moves {
+				safe = safe || !losesGame(state, move)
+			}
+
+			if move := (HeavyPolicy{}).ChooseMove(state, moves); safe && losesGame(state, move) {
+				t.Fatalf("The heavy policy played %v, letting the opponent win the game in:\n%s", *move, FormatPosition(state))
+			}
+			state.Play(moves[random.Intn(len(moves))])
+		}
+	}
+}
+
+func TestSimulateWithPolicies(t *testing.T) {
+	for _, policy := range []PlayoutPolicy{UniformPolicy{}, HeavyPolicy{}, EpsilonGreedyPolicy{Epsilon: 0.5, Greedy: HeavyPolicy{}}} {
+		var played []Move
+		state := NewGame()
+
+		winner, moves := simulate(state, policy, &played)
+		if !state.IsOver() || state.Board.HasWinner() != winner {
+			t.Errorf("%T: simulate() did not play the game to the end", policy)
+		}
+
+		if len(played) != int(moves) || len(played) != 81-len(state.Board.AllPossibleMoves()) {
+			t.Errorf("%T: simulate() made %v moves, but recorded %d", policy, moves, len(played))
+		}
+	}
+
+	if _, err := NewPlayoutPolicy("clever", 0); err == nil {
+		t.Error("Expected an error for an unknown playout policy!")
+	}
+}
+
+func BenchmarkSimulateUniform(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		simulate(NewGame(), UniformPolicy{}, nil)
+	}
+}
+
+func BenchmarkSimulateHeavy(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		simulate(NewGame(), HeavyPolicy{}, nil)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+// PUCT_EXPLORATION weighs a move's prior against the value
+// the search has found for it in PUCT's selection.
+const PUCT_EXPLORATION = 1.5
+
+// puctNode is a single position in a PUCT search tree. Its value
+// is counted from the point of view of the player who made move.
+type puctNode struct {
+	move     Move
+	prior    float64
+	visits   float64
+	valueSum float64
+	children []*puctNode
+	expanded bool
+}
+
+// mean returns the average value of the node, 0 if it has not been visited.
+func (node *puctNode) mean() float64 {
+	if node.visits == 0 {
+		return 0
+	}
+
+	return node.valueSum / node.visits
+}
+
+// selectChild picks the child maximising its mean value plus an
+// exploration bonus, which is larger for moves with a high prior.
+func (node *puctNode) selectChild() *puctNode {
+	var bestChild *puctNode
+	bestValue := math.Inf(-1)
+	sqrtVisits := math.Sqrt(node.visits)
+
+	for _, child := range node.children {
+		value := child.mean() + PUCT_EXPLORATION*child.prior*sqrtVisits/(1+child.visits)
+		if value > bestValue {
+			bestValue = value
+			bestChild = child
+		}
+	}
+
+	return bestChild
+}
+
+// mostVisitedChild returns the child searched the most, nil if there are none.
+func (node *puctNode) mostVisitedChild() *puctNode {
+	var bestChild *puctNode
+	for _, child := range node.children {
+		if bestChild == nil || child.visits > bestChild.visits {
+			bestChild = child
+		}
+	}
+
+	return bestChild
+}
+
+// size returns the number of expanded nodes in the subtree.
+func (node *puctNode) size() int {
+	if !node.expanded {
+		return 0
+	}
+
+	size := 1
+	for _, child := range node.children {
+		size += child.size()
+	}
+
+	return size
+}
+
+// linearValuePolicy turns an Evaluator into a ValuePolicy, with the same
+// prior for every move. The PUCT search uses it when it has no Network.
+type linearValuePolicy struct {
+	evaluator *Evaluator
+}
+
+// Predict implements ValuePolicy.
+func (policy linearValuePolicy) Predict(state *GameState, moves []*Move) (float64, []float64) {
+	priors := make([]float64, len(moves))
+	for i := range priors {
+		priors[i] = 1 / float64(len(moves))
+	}
+
+	return 2*policy.evaluator.Evaluate(state) - 1, priors
+}
+
+// puctPlayout walks down the tree from root, at state, to a position it has
+// not evaluated yet, evaluates it with valuePolicy and updates the values on
+// the way back up.
+func puctPlayout(root *puctNode, state GameState, valuePolicy ValuePolicy) {
+	path := []*puctNode{root}
+	node := root
+	for node.expanded && len(node.children) > 0 {
+		node = node.selectChild()
+		state.Play(&node.move)
+		path = append(path, node)
+	}
+
+	// The value of the position for the player to move.
+	var value float64
+	if winner := state.Winner(); winner != EMPTY {
+		// Under the standard rules, only the player who just moved can
+		// have completed a line.
+		value = -1
+		if winner == PlayerMark(state.Player) {
+			value = 1
+		}
+	} else if moves := state.ValidMoves(); len(moves) > 0 {
+		var priors []float64
+		value, priors = valuePolicy.Predict(&state, moves)
+		if !node.expanded {
+			for i, move := range moves {
+				node.children = append(node.children, &puctNode{move: *move, prior: priors[i]})
+			}
+		}
+	}
+	node.expanded = true
+
+	for i := len(path) - 1; i >= 0; i-- {
+		value = -value
+		path[i].visits += 1
+		path[i].valueSum += value
+	}
+}
+
+// PUCTAnalysis searches state like AlphaZero does: a tree search guided
+// by the move priors of config.Network, which also evaluates the positions
+// the search reaches instead of simulating the rest of the game. Without
+// a Network, the Evaluator (or DefaultEvaluator) evaluates the positions
+// and every move gets the same prior. The search stops once ThinkTime or
+// MaxPlayouts is used up. The moves of config.Book are played without
+// searching, and once few enough empty squares are left, the endgame
+// solver takes over instead.
+//
+// Candidates are ranked by visits and scored by their mean value, from -1
+// to 1, with the expected result as their WinRate.
+func PUCTAnalysis(state *GameState, config SearchConfig) *Analysis {
+	start := time.Now()
+	if config.ThinkTime <= 0 && config.MaxPlayouts == 0 {
+		// Nothing would end the search, so it thinks as long as MonteCarloBot.
+		config.ThinkTime = time.Duration(TIME_TO_THINK * float64(time.Second))
+	}
+	if analysis := config.Book.Analysis(state); analysis != nil {
+		slog.Info("PUCT search played from the opening book", "search", analysis)
+		return analysis
+	}
+
+	if analysis := solveEndgame(state, config.SolverThreshold, config.deadline(start)); analysis != nil {
+		slog.Info("PUCT search solved the position", "search", analysis)
+		return analysis
+	}
+
+	valuePolicy := config.Network
+	if valuePolicy == nil {
+		evaluator := config.Evaluator
+		if evaluator == nil {
+			evaluator = DefaultEvaluator
+		}
+		valuePolicy = linearValuePolicy{evaluator}
+	}
+
+	root := &puctNode{}
+	playouts := 0
+	for config.MaxPlayouts == 0 || playouts < config.MaxPlayouts {
+		if playouts%64 == 0 && config.ThinkTime > 0 && time.Since(start) > config.ThinkTime {
+			break
+		}
+
+		puctPlayout(root, *state, valuePolicy)
+		playouts += 1
+	}
+
+	children := append([]*puctNode(nil), root.children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].visits > children[j].visits
+	})
+
+	analysis := &Analysis{Playouts: playouts, TimeUsed: time.Since(start), TreeSize: root.size()}
+	for _, child := range children {
+		winRate := (child.mean() + 1) / 2
+		analysis.Candidates = append(analysis.Candidates, CandidateMove{
+			Move:     child.move,
+			Visits:   int(child.visits),
+			WinRate:  winRate,
+			LossRate: 1 - winRate,
+			Score:    child.mean(),
+		})
+	}
+
+	for node := root.mostVisitedChild(); node != nil && node.visits > 0; node = node.mostVisitedChild() {
+		analysis.PrincipalVariation = append(analysis.PrincipalVariation, node.move)
+	}
+
+	slog.Info("PUCT search finished", "search", analysis)
+	return analysis
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPUCTFindsWinningMove(t *testing.T) {
+	state := NewGame()
+
+	// Player 1 has won boards (0,0) and (1,1), and only needs to
+	// win board (2,2) (top left tile missing) to win the game.
+	for i := 0; i < 3; i++ {
+		state.Board[0][0][i][i] = PLAYER_1_CONTROLLED
+		state.Board[1][1][i][i] = PLAYER_1_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_1_CONTROLLED
+	state.Board[2][2][2][2] = PLAYER_1_CONTROLLED
+	state.LastMove = Move{0, 1, 2, 2}
+
+	analysis := PUCTAnalysis(state, SearchConfig{MaxPlayouts: 200})
+	if move := analysis.BestMove(); *move != (Move{2, 2, 0, 0}) {
+		t.Error("Expected the search to find the winning move (2,2,0,0), it played", *move)
+	}
+
+	if analysis.Playouts != 200 || analysis.Candidates[0].WinRate != 1 {
+		t.Error("Expected 200 playouts and a certain win, got", analysis.Playouts, "playouts and a win rate of", analysis.Candidates[0].WinRate)
+	}
+}
+
+func TestPUCTWithNetwork(t *testing.T) {
+	config := SearchConfig{MaxPlayouts: 300, Network: NewNetwork([]int{16}, rand.New(rand.NewSource(1)))}
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	analysis := PUCTAnalysis(state, config)
+	if move := analysis.BestMove(); move.BoardX != 1 || move.BoardY != 1 {
+		t.Error("The search did not stick to the board it was forced to, it played", *move)
+	}
+
+	visits := 0
+	for _, candidate := range analysis.Candidates {
+		visits += candidate.Visits
+	}
+	if visits != config.MaxPlayouts-1 || analysis.TreeSize != config.MaxPlayouts {
+		t.Error("Expected every playout but the first to visit a move and expand a node, got", visits, "visits and", analysis.TreeSize, "nodes")
+	}
+}
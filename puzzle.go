@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PUZZLE_VERSION is the version of the puzzle file format written by SavePuzzles.
+const PUZZLE_VERSION = 2
+
+// DEFAULT_PUZZLE_PLIES is how many moves deep MinePuzzles looks for a win by default.
+const DEFAULT_PUZZLE_PLIES = 3
+
+// MAX_PUZZLE_NODES is how many positions the puzzle search may visit for a
+// single position before it gives up on it, which keeps positions where the
+// player to move may play on any board from taking forever.
+const MAX_PUZZLE_NODES = 200000
+
+// A Puzzle is a position in which the player to move has a single move
+// winning the game within Plies moves (counting both players' moves, and
+// the winning move itself): 1 for a move that wins at once, 3 for a move
+// after which every reply loses to the next move, and so on.
+type Puzzle struct {
+	State    GameState
+	Solution Move
+	Plies    int
+}
+
+// puzzleSearch proves that a player can force a win within a number of
+// moves, visiting at most maxNodes positions.
+type puzzleSearch struct {
+	nodes    int
+	maxNodes int
+	aborted  bool
+}
+
+// wins returns true if the player to move in state can win within plies moves.
+func (search *puzzleSearch) wins(state *GameState, plies int) bool {
+	for _, move := range state.ValidMoves() {
+		if search.winsWith(state, move, plies) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// winsWith returns true if move wins the game within plies moves in state.
+func (search *puzzleSearch) winsWith(state *GameState, move *Move, plies int) bool {
+	search.nodes += 1
+	if search.nodes > search.maxNodes {
+		search.aborted = true
+	}
+	if search.aborted || plies < 1 {
+		return false
+	}
+
+	next := *state
+	next.Play(move)
+	if next.Winner() == PlayerMark(state.Player) {
+		return true
+	}
+	if plies < 3 || next.IsOver() {
+		return false
+	}
+
+	// Every reply of the opponent must lose.
+	for _, reply := range next.ValidMoves() {
+		afterReply := next
+		afterReply.Play(reply)
+		if afterReply.Winner() == PlayerMark(state.Player) {
+			// A reply completing a line loses under the misère rules.
+			continue
+		}
+		if afterReply.IsOver() || !search.wins(&afterReply, plies-2) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// winningMoves returns the moves winning within plies moves in state, and
+// false if the search ran out of nodes before it could tell.
+func (search *puzzleSearch) winningMoves(state *GameState, plies int) ([]Move, bool) {
+	var winning []Move
+	for _, move := range state.ValidMoves() {
+		if search.winsWith(state, move, plies) {
+			winning = append(winning, *move)
+		}
+	}
+
+	return winning, !search.aborted
+}
+
+// FindPuzzle returns the Puzzle in state if the player to move has a single
+// move winning within the fewest moves, at most maxPlies, that any move
+// wins in. The last value is false if there is no puzzle in state.
+func FindPuzzle(state *GameState, maxPlies int) (Puzzle, bool) {
+	if state.IsOver() {
+		return Puzzle{}, false
+	}
+
+	for plies := 1; plies <= maxPlies; plies += 2 {
+		search := &puzzleSearch{maxNodes: MAX_PUZZLE_NODES}
+		winning, complete := search.winningMoves(state, plies)
+		if !complete || len(winning) > 1 {
+			return Puzzle{}, false
+		}
+
+		if len(winning) == 1 {
+			return Puzzle{State: *state, Solution: winning[0], Plies: plies}, true
+		}
+	}
+
+	return Puzzle{}, false
+}
+
+// MinePuzzles returns the puzzles found by FindPuzzle in states, leaving out
+// positions which are a rotation or reflection of one already found.
+func MinePuzzles(states []GameState, maxPlies int) []Puzzle {
+	var puzzles []Puzzle
+	seen := make(map[GameState]bool)
+	for i := range states {
+		key, _ := Canonical(&states[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if puzzle, ok := FindPuzzle(&states[i], maxPlies); ok {
+			puzzles = append(puzzles, puzzle)
+		}
+	}
+
+	slog.Info("Mined the puzzles", "positions", len(seen), "puzzles", len(puzzles))
+	return puzzles
+}
+
+// SavePuzzles writes puzzles to w: a "version 2" line, a "rules" line naming
+// the rules of the puzzles as ParseRules reads them, then one line for
+// every puzzle, with the position in the notation of FormatPosition, the
+// solution ("boardX boardY tileX tileY") and the number of plies it wins in.
+// Lines starting with a '#' are comments.
+func SavePuzzles(w io.Writer, puzzles []Puzzle) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintln(buffer, "# Ultimate Tic-Tac-Toe puzzles: position, winning move, plies to win")
+	fmt.Fprintf(buffer, "version %d\n", PUZZLE_VERSION)
+
+	rules := DefaultRules
+	if len(puzzles) > 0 {
+		rules = puzzles[0].State.Rules
+	}
+	fmt.Fprintf(buffer, "rules %s\n", rules)
+
+	for _, puzzle := range puzzles {
+		m := puzzle.Solution
+		fmt.Fprintf(buffer, "%s %d %d %d %d %d\n", FormatPosition(&puzzle.State), m.BoardX, m.BoardY, m.TileX, m.TileY, puzzle.Plies)
+	}
+
+	return buffer.Flush()
+}
+
+// ReadPuzzles reads puzzles written by SavePuzzles, under the same rules.
+func ReadPuzzles(r io.Reader) ([]Puzzle, error) {
+	var puzzles []Puzzle
+	sawVersion, sawRules := false, false
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !sawVersion {
+			if len(fields) != 2 || fields[0] != "version" || fields[1] != strconv.Itoa(PUZZLE_VERSION) {
+				return nil, fmt.Errorf("expected version %d puzzles, got %q", PUZZLE_VERSION, line)
+			}
+			sawVersion = true
+			continue
+		}
+
+		if !sawRules {
+			if err := readRulesLine(fields); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			sawRules = true
+			continue
+		}
+
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("line %d: expected a position, a move and the plies to win, got %q", lineNumber, line)
+		}
+
+		state, err := ParsePosition(strings.Join(fields[:4], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		var numbers [5]int
+		for i, field := range fields[4:] {
+			if numbers[i], err = strconv.Atoi(field); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+		}
+
+		puzzle := Puzzle{State: *state, Solution: Move{numbers[0], numbers[1], numbers[2], numbers[3]}, Plies: numbers[4]}
+		if !isValidMove(state, &puzzle.Solution) {
+			return nil, fmt.Errorf("line %d: %v is not a legal move", lineNumber, puzzle.Solution)
+		}
+		puzzles = append(puzzles, puzzle)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !sawRules {
+		return nil, fmt.Errorf("no puzzles found")
+	}
+
+	return puzzles, nil
+}
+
+// LoadPuzzles reads the puzzle file at path.
+func LoadPuzzles(path string) ([]Puzzle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadPuzzles(file)
+}
+
+// PuzzleResult counts the puzzles a bot was given and solved, by the
+// number of plies they win in.
+type PuzzleResult struct {
+	Solved map[int]int
+	Total  map[int]int
+}
+
+// String formats the share of the puzzles solved, overall and by plies.
+func (result PuzzleResult) String() string {
+	var buffer bytes.Buffer
+	var solved, total int
+	var plies []int
+	for n, count := range result.Total {
+		solved += result.Solved[n]
+		total += count
+		plies = append(plies, n)
+	}
+	sort.Ints(plies)
+
+	if total == 0 {
+		return "solved 0 of 0 puzzles\n"
+	}
+
+	fmt.Fprintf(&buffer, "solved %d of %d puzzles (%.1f%%)\n", solved, total, 100*float64(solved)/float64(total))
+	for _, n := range plies {
+		fmt.Fprintf(&buffer, "  win in %d: %d of %d (%.1f%%)\n", n, result.Solved[n], result.Total[n], 100*float64(result.Solved[n])/float64(result.Total[n]))
+	}
+
+	return buffer.String()
+}
+
+// GradePuzzles gives every puzzle to analyst and counts the puzzles for
+// which the move it ranks best is the solution.
+func GradePuzzles(analyst Analyst, puzzles []Puzzle) PuzzleResult {
+	result := PuzzleResult{Solved: make(map[int]int), Total: make(map[int]int)}
+	for i := range puzzles {
+		state := puzzles[i].State
+		result.Total[puzzles[i].Plies] += 1
+		if move := analyst(&state).BestMove(); move != nil && *move == puzzles[i].Solution {
+			result.Solved[puzzles[i].Plies] += 1
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// winningPosition returns a position where X has won the boards (0,0) and
+// (0,1) and is sent to board (0,2), where it has the squares in tiles.
+func winningPosition(tiles ...Move) *GameState {
+	state := NewGame()
+	for _, board := range []int{0, 1} {
+		for tile := 0; tile < 3; tile++ {
+			state.Board[0][board][tile][tile] = PLAYER_1_CONTROLLED
+		}
+	}
+	for _, tile := range tiles {
+		state.Board[0][2][tile.TileX][tile.TileY] = PLAYER_1_CONTROLLED
+	}
+	state.Board[2][2][1][1] = PLAYER_2_CONTROLLED
+	state.LastMove = Move{0, 0, 0, 2}
+
+	return state
+}
+
+func TestFindPuzzle(t *testing.T) {
+	state := winningPosition(Move{0, 2, 0, 0}, Move{0, 2, 0, 1})
+	puzzle, ok := FindPuzzle(state, 3)
+	if !ok || puzzle.Solution != (Move{0, 2, 0, 2}) || puzzle.Plies != 1 {
+		t.Error("Expected the puzzle 0 2 0 2 winning at once, got", puzzle, ok)
+	}
+
+	// Several moves win at once, so there is no single solution.
+	state = winningPosition(Move{0, 2, 0, 0}, Move{0, 2, 0, 1}, Move{0, 2, 1, 1})
+	if puzzle, ok := FindPuzzle(state, 3); ok {
+		t.Error("Found a puzzle with two solutions:", puzzle)
+	}
+
+	if _, ok := FindPuzzle(NewGame(), 3); ok {
+		t.Error("Found a puzzle in a new game")
+	}
+}
+
+func TestMinePuzzles(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	var states []GameState
+	for i := 0; i < 300; i++ {
+		state := randomPosition(random)
+		states = append(states, *state, Symmetry(i%NUM_SYMMETRIES).State(state))
+	}
+
+	puzzles := MinePuzzles(states, 3)
+	if len(puzzles) == 0 {
+		t.Fatal("Found no puzzles in", len(states), "positions")
+	}
+
+	seen := make(map[GameState]bool)
+	for _, puzzle := range puzzles {
+		key, _ := Canonical(&puzzle.State)
+		if seen[key] {
+			t.Error("Found the same puzzle twice:", FormatPosition(&puzzle.State))
+		}
+		seen[key] = true
+
+		search := &puzzleSearch{maxNodes: MAX_PUZZLE_NODES}
+		winning, _ := search.winningMoves(&puzzle.State, puzzle.Plies)
+		if len(winning) != 1 || winning[0] != puzzle.Solution {
+			t.Error("Expected", puzzle.Solution, "to be the only move winning within", puzzle.Plies, "plies in", FormatPosition(&puzzle.State), "got", winning)
+		}
+		if puzzle.Plies > 1 && search.wins(&puzzle.State, puzzle.Plies-2) {
+			t.Error("There is a faster win than", puzzle.Plies, "plies in", FormatPosition(&puzzle.State))
+		}
+	}
+}
+
+func TestPuzzleSaveAndGrade(t *testing.T) {
+	puzzles := []Puzzle{
+		{*winningPosition(Move{0, 2, 0, 0}, Move{0, 2, 0, 1}), Move{0, 2, 0, 2}, 1},
+		{*winningPosition(Move{0, 2, 1, 1}), Move{0, 2, 2, 2}, 3},
+	}
+
+	var buffer bytes.Buffer
+	if err := SavePuzzles(&buffer, puzzles); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPuzzles(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(puzzles) || read[0] != puzzles[0] || read[1] != puzzles[1] {
+		t.Fatal("Saved", puzzles, "but read", read)
+	}
+
+	for _, input := range []string{
+		"",
+		"version 2\n",
+		"version 1\nX 0 2 X--X-----/X---X---X/XX-------/---------/---------/---------/---------/---------/----O---- 0 2 0 2 1\n",
+		"version 2\nrules free\nX 0 2 X--X-----/X---X---X/XX-------/---------/---------/---------/---------/---------/----O---- 0 2 0 2 1\n",
+	} {
+		if _, err := ReadPuzzles(strings.NewReader(input)); err == nil {
+			t.Errorf("Read puzzles from %q", input)
+		}
+	}
+
+	solver := func(state *GameState) *Analysis {
+		return moveAnalysis(&Move{0, 2, 0, 2})
+	}
+	result := GradePuzzles(solver, puzzles)
+	if result.Solved[1] != 1 || result.Total[1] != 1 || result.Solved[3] != 0 || result.Total[3] != 1 {
+		t.Error("Unexpected result", result.Solved, result.Total)
+	}
+	if result.String() != "solved 1 of 2 puzzles (50.0%)\n  win in 1: 1 of 1 (100.0%)\n  win in 3: 0 of 1 (0.0%)\n" {
+		t.Errorf("Unexpected summary %q", result.String())
+	}
+
+	if empty := GradePuzzles(solver, nil).String(); empty != "solved 0 of 0 puzzles\n" {
+		t.Errorf("Unexpected summary %q without puzzles", empty)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+)
+
+// GIF_FRAME_DELAY is how long every move is shown in an animated game, and
+// GIF_FINAL_DELAY how long the final position is, in hundredths of a second.
+const (
+	GIF_FRAME_DELAY = 80
+	GIF_FINAL_DELAY = 400
+)
+
+// hexColor turns a color written as "#rrggbb" into a color.RGBA.
+func hexColor(hex string) color.RGBA {
+	value, _ := strconv.ParseUint(hex[1:], 16, 32)
+	return color.RGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 255}
+}
+
+// gifPalette holds the colors of the rendered board, and the web-safe
+// colors for the blends of the heatmap and the won boards.
+var gifPalette = append(color.Palette{
+	color.White,
+	hexColor(GRID_COLOR),
+	hexColor(PLAYER_1_COLOR),
+	hexColor(PLAYER_2_COLOR),
+	hexColor(HIGHLIGHT_COLOR),
+	hexColor(PLAYED_COLOR),
+	hexColor(HEATMAP_COLOR),
+	hexColor(ARROW_COLOR),
+}, palette.WebSafe...)
+
+// blend paints c over the pixel at x, y of img, with the given opacity.
+func blend(img *image.RGBA, x, y int, c color.RGBA, opacity float64) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+
+	under := img.RGBAAt(x, y)
+	mix := func(over, under uint8) uint8 {
+		return uint8(math.Round(opacity*float64(over) + (1-opacity)*float64(under)))
+	}
+	img.SetRGBA(x, y, color.RGBA{mix(c.R, under.R), mix(c.G, under.G), mix(c.B, under.B), 255})
+}
+
+// fillRect paints c over the size by size square at x, y of img.
+func fillRect(img *image.RGBA, x, y, size int, c color.RGBA, opacity float64) {
+	for py := y; py < y+size; py++ {
+		for px := x; px < x+size; px++ {
+			blend(img, px, py, c, opacity)
+		}
+	}
+}
+
+// drawShape paints c on every pixel of img around x, y, at most reach
+// pixels away, which inside says belongs to the shape.
+func drawShape(img *image.RGBA, x, y, reach float64, c color.RGBA, inside func(px, py float64) bool) {
+	for py := int(y - reach); py <= int(y+reach)+1; py++ {
+		for px := int(x - reach); px <= int(x+reach)+1; px++ {
+			if inside(float64(px)+0.5, float64(py)+0.5) {
+				blend(img, px, py, c, 1)
+			}
+		}
+	}
+}
+
+// drawLine paints a line of the given width from x1, y1 to x2, y2.
+func drawLine(img *image.RGBA, x1, y1, x2, y2, width float64, c color.RGBA) {
+	dx, dy := x2-x1, y2-y1
+	length := dx*dx + dy*dy
+	reach := math.Hypot(dx, dy)/2 + width
+	drawShape(img, (x1+x2)/2, (y1+y2)/2, reach, c, func(px, py float64) bool {
+		// The distance from the pixel to the closest point of the line.
+		t := 0.0
+		if length > 0 {
+			t = math.Max(0, math.Min(1, ((px-x1)*dx+(py-y1)*dy)/length))
+		}
+		return math.Hypot(px-x1-t*dx, py-y1-t*dy) <= width/2
+	})
+}
+
+// drawRing paints a circle of radius r around x, y with the given width.
+func drawRing(img *image.RGBA, x, y, r, width float64, c color.RGBA) {
+	drawShape(img, x, y, r+width, c, func(px, py float64) bool {
+		return math.Abs(math.Hypot(px-x, py-y)-r) <= width/2
+	})
+}
+
+// drawMark paints the mark of square (an X, an O or nothing if it is EMPTY)
+// in the size by size square at x, y, like writeSVGMark.
+func drawMark(img *image.RGBA, square, x, y, size int) {
+	padding := float64(size / 5)
+	left, top, right, bottom := float64(x)+padding, float64(y)+padding, float64(x+size)-padding, float64(y+size)-padding
+	width := float64(size / 10)
+	switch square {
+	case PLAYER_1_CONTROLLED:
+		drawLine(img, left, top, right, bottom, width, hexColor(PLAYER_1_COLOR))
+		drawLine(img, right, top, left, bottom, width, hexColor(PLAYER_1_COLOR))
+	case PLAYER_2_CONTROLLED:
+		drawRing(img, float64(x)+float64(size)/2, float64(y)+float64(size)/2, float64(size)/2-padding, width, hexColor(PLAYER_2_COLOR))
+	}
+}
+
+// RenderImage draws board as an image, the same way RenderSVG does.
+func RenderImage(board *UltimateBoard, options RenderOptions) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, BOARD_SIZE, BOARD_SIZE))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for _, highlighted := range highlightedBoards(options.position(board)) {
+		x, y := boardOrigin(highlighted[0], highlighted[1])
+		fillRect(img, x, y, 3*SQUARE_SIZE, hexColor(HIGHLIGHT_COLOR), 1)
+	}
+
+	if options.Played != nil {
+		x, y := squareOrigin(*options.Played)
+		fillRect(img, x, y, SQUARE_SIZE, hexColor(PLAYED_COLOR), 1)
+	}
+
+	for i, weight := range candidateWeights(options.Candidates) {
+		x, y := squareOrigin(options.Candidates[i].Move)
+		fillRect(img, x, y, SQUARE_SIZE, hexColor(HEATMAP_COLOR), 0.1+0.7*weight)
+	}
+
+	// The grid, with every third line thicker.
+	for line := 0; line <= 9; line++ {
+		width := 1.0
+		if line%3 == 0 {
+			width = 4
+		}
+		offset := float64(BOARD_MARGIN + line*SQUARE_SIZE)
+		start, end := float64(BOARD_MARGIN), float64(BOARD_MARGIN+9*SQUARE_SIZE)
+		drawLine(img, offset, start, offset, end, width, hexColor(GRID_COLOR))
+		drawLine(img, start, offset, end, offset, width, hexColor(GRID_COLOR))
+	}
+
+	for bx := 0; bx < 3; bx++ {
+		for by := 0; by < 3; by++ {
+			for tx := 0; tx < 3; tx++ {
+				for ty := 0; ty < 3; ty++ {
+					x, y := squareOrigin(Move{bx, by, tx, ty})
+					drawMark(img, board[bx][by][tx][ty], x, y, SQUARE_SIZE)
+				}
+			}
+
+			// Cover the pieces of a won board, inside its thick lines, with the winner's mark.
+			if winner := board[bx][by].HasWinner(); winner != EMPTY {
+				x, y := boardOrigin(bx, by)
+				fillRect(img, x+2, y+2, 3*SQUARE_SIZE-4, color.RGBA{255, 255, 255, 255}, 0.7)
+				drawMark(img, winner, x, y, 3*SQUARE_SIZE)
+			}
+		}
+	}
+
+	for i := 0; i < options.Arrows && i < len(options.Candidates); i++ {
+		move := options.Candidates[i].Move
+		x1, y1 := squareOrigin(move)
+		x2, y2 := boardOrigin(move.TileX, move.TileY)
+		drawArrow(img, float64(x1+SQUARE_SIZE/2), float64(y1+SQUARE_SIZE/2), float64(x2+3*SQUARE_SIZE/2), float64(y2+3*SQUARE_SIZE/2))
+	}
+
+	return img
+}
+
+// drawArrow paints an arrow from x1, y1 to x2, y2, with its head at x2, y2.
+func drawArrow(img *image.RGBA, x1, y1, x2, y2 float64) {
+	arrowColor := hexColor(ARROW_COLOR)
+	drawLine(img, x1, y1, x2, y2, 4, arrowColor)
+
+	angle := math.Atan2(y2-y1, x2-x1)
+	for _, side := range []float64{-1, 1} {
+		headAngle := angle + math.Pi + side*math.Pi/6
+		drawLine(img, x2, y2, x2+12*math.Cos(headAngle), y2+12*math.Sin(headAngle), 4, arrowColor)
+	}
+}
+
+// RenderPNG draws board as a PNG image to w, the same way RenderSVG does.
+func RenderPNG(w io.Writer, board *UltimateBoard, options RenderOptions) error {
+	return png.Encode(w, RenderImage(board, options))
+}
+
+// RenderGIF draws record as an animated GIF to w: the empty board, then the
+// position after every move, with the move marked and the board the next
+// player is sent to highlighted. The final position is shown the longest.
+func RenderGIF(w io.Writer, record GameRecord) error {
+	positions, err := record.Positions()
+	if err != nil {
+		return err
+	}
+
+	animation := &gif.GIF{}
+	for i := range positions {
+		options := RenderOptions{LastMove: &positions[i].LastMove}
+		if i > 0 {
+			options.Played = &record.Moves[i-1]
+		}
+
+		img := RenderImage(&positions[i].Board, options)
+		frame := image.NewPaletted(img.Bounds(), gifPalette)
+		draw.Draw(frame, frame.Bounds(), img, image.Point{}, draw.Src)
+
+		delay := GIF_FRAME_DELAY
+		if i == len(positions)-1 {
+			delay = GIF_FINAL_DELAY
+		}
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	return gif.EncodeAll(w, animation)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestRenderImage(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 0, 1, 1})
+
+	img := RenderImage(&state.Board, RenderOptions{LastMove: &state.LastMove, Played: &state.LastMove})
+	if img.Bounds().Dx() != BOARD_SIZE || img.Bounds().Dy() != BOARD_SIZE {
+		t.Fatal("Unexpected image size", img.Bounds())
+	}
+
+	// The center of an X is drawn over the square of the move.
+	x, y := squareOrigin(Move{0, 0, 1, 1})
+	if got := img.RGBAAt(x+SQUARE_SIZE/2, y+SQUARE_SIZE/2); got != hexColor(PLAYER_1_COLOR) {
+		t.Error("Expected an X in the middle of the move's square, got", got)
+	}
+	if got := img.RGBAAt(x+SQUARE_SIZE/2, y+3); got != hexColor(PLAYED_COLOR) {
+		t.Error("Expected the move's square to be marked, got", got)
+	}
+
+	// O is sent to the center board, no other board is highlighted.
+	x, y = squareOrigin(Move{1, 1, 0, 1})
+	if got := img.RGBAAt(x+SQUARE_SIZE/2, y+SQUARE_SIZE/2); got != hexColor(HIGHLIGHT_COLOR) {
+		t.Error("Expected the center board to be highlighted, got", got)
+	}
+	x, y = squareOrigin(Move{2, 2, 0, 1})
+	if got := img.RGBAAt(x+SQUARE_SIZE/2, y+SQUARE_SIZE/2); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Error("Expected the other boards to be white, got", got)
+	}
+
+	var buffer bytes.Buffer
+	if err := RenderPNG(&buffer, &state.Board, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(&buffer); err != nil {
+		t.Error("Invalid PNG image:", err)
+	}
+}
+
+func TestRenderGIF(t *testing.T) {
+	record := GameRecord{Moves: []Move{{1, 1, 0, 0}, {0, 0, 2, 2}, {2, 2, 1, 1}}}
+
+	var buffer bytes.Buffer
+	if err := RenderGIF(&buffer, record); err != nil {
+		t.Fatal(err)
+	}
+
+	animation, err := gif.DecodeAll(&buffer)
+	if err != nil {
+		t.Fatal("Invalid GIF image:", err)
+	}
+	if len(animation.Image) != 4 || animation.Delay[3] != GIF_FINAL_DELAY {
+		t.Error("Expected the empty board and 3 moves, got", len(animation.Image), "frames with delays", animation.Delay)
+	}
+
+	// Frames only use colors of the palette, so the players' colors come out exact.
+	x, y := squareOrigin(record.Moves[2])
+	if got := animation.Image[3].At(x+SQUARE_SIZE/2, y+SQUARE_SIZE/2); !sameColor(got, hexColor(PLAYER_1_COLOR)) {
+		t.Error("Expected an X on the last frame, got", got)
+	}
+
+	if err := RenderGIF(&buffer, GameRecord{Moves: []Move{{1, 1, 0, 0}, {1, 1, 0, 1}}}); err == nil {
+		t.Error("Rendered a game with an illegal move")
+	}
+}
+
+// sameColor returns true if a and b are the same color.
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GameRecord is a game as the moves made in it, from the first move on.
+type GameRecord struct {
+	Moves []Move
+}
+
+// RecordGame plays a game between player1 (X) and player2 (O) and returns
+// its record.
+func RecordGame(player1, player2 Bot) GameRecord {
+	var record GameRecord
+	state := NewGame()
+	for !state.IsOver() {
+		var move *Move
+		if state.Player == 1 {
+			move = player1(state)
+		} else {
+			move = player2(state)
+		}
+
+		record.Moves = append(record.Moves, *move)
+		state.Play(move)
+	}
+
+	return record
+}
+
+// Positions returns the position before every move of the game and the
+// final position, or an error if one of the moves is not legal.
+func (record GameRecord) Positions() ([]GameState, error) {
+	state := NewGame()
+	positions := []GameState{*state}
+	for i := range record.Moves {
+		if state.IsOver() || !isValidMove(state, &record.Moves[i]) {
+			return nil, fmt.Errorf("move %d (%v) is not a legal move", i+1, record.Moves[i])
+		}
+
+		state.Play(&record.Moves[i])
+		positions = append(positions, *state)
+	}
+
+	return positions, nil
+}
+
+// Save writes the record to w, one move per line in the same format the
+// bot emits its moves in: "boardX boardY tileX tileY".
+func (record GameRecord) Save(w io.Writer) error {
+	buffer := bufio.NewWriter(w)
+	for _, move := range record.Moves {
+		fmt.Fprintf(buffer, "%d %d %d %d\n", move.BoardX, move.BoardY, move.TileX, move.TileY)
+	}
+
+	return buffer.Flush()
+}
+
+// ReadGameRecord reads a record written by Save, checking that every
+// move is legal. Empty lines and lines starting with a '#' are skipped.
+func ReadGameRecord(r io.Reader) (GameRecord, error) {
+	var record GameRecord
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return record, fmt.Errorf("line %d: expected a move, got %q", lineNumber, line)
+		}
+
+		var numbers [4]int
+		for i, field := range fields {
+			var err error
+			if numbers[i], err = strconv.Atoi(field); err != nil {
+				return record, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+		}
+		record.Moves = append(record.Moves, Move{numbers[0], numbers[1], numbers[2], numbers[3]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return record, err
+	}
+
+	_, err := record.Positions()
+	return record, err
+}
+
+// LoadGameRecord reads the game record file at path.
+func LoadGameRecord(path string) (GameRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return GameRecord{}, err
+	}
+	defer file.Close()
+
+	return ReadGameRecord(file)
+}
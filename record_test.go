@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGameRecord(t *testing.T) {
+	random := analystBot(Analysts["random"](DefaultSearchConfig))
+	record := RecordGame(random, random)
+
+	positions, err := record.Positions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != len(record.Moves)+1 || !positions[len(positions)-1].IsOver() {
+		t.Fatal("Expected the positions of a whole game, got", len(positions), "for", len(record.Moves), "moves")
+	}
+
+	var buffer bytes.Buffer
+	if err := record.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadGameRecord(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Moves) != len(record.Moves) {
+		t.Fatal("Saved", len(record.Moves), "moves, read", len(read.Moves))
+	}
+	for i := range read.Moves {
+		if read.Moves[i] != record.Moves[i] {
+			t.Error("Saved move", i+1, "as", record.Moves[i], "but read", read.Moves[i])
+		}
+	}
+
+	for _, input := range []string{
+		"1 1 1\n",
+		"1 1 1 x\n",
+		"# Sent to board (1,1), not (2,2)\n1 1 1 1\n2 2 0 0\n",
+	} {
+		if _, err := ReadGameRecord(strings.NewReader(input)); err == nil {
+			t.Errorf("Read a game record from %q", input)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MAX_RECURSIVE_DEPTH is the most levels a RecursiveBoard can have
+// (9^4 = 6561 squares).
+const MAX_RECURSIVE_DEPTH = 4
+
+// RecursiveBoard is a game of Tic-Tac-Toe on 3x3 boards nested Depth levels
+// deep: every cell of a board is a board of the next level down, and the
+// cells of the boards at the bottom are the squares. Depth 2 is the standard
+// game, depth 3 "ultimate-ultimate" Tic-Tac-Toe.
+//
+// A square is numbered by its path from the top: the cell it is in on the
+// top board, then on the board below and so on, each from 0 to 8 row by row,
+// read as the digits of a number in base 9. The forced board rule applies on
+// every level: after a move on path a1 a2 ... aN, the next player has to play
+// on the bottom board at path a2 ... aN. If it (or a board above it) is won
+// or full, the player may play anywhere on the board at a2 ... aN-1 instead,
+// and so on up to the top board.
+type RecursiveBoard struct {
+	Depth    int
+	Squares  []int // Every square, indexed by its path
+	LastMove int   // The square of the last move, or -1 at the start
+	Player   int   // The player (1 or 2) whose turn it is
+
+	// The winner of every board (EMPTY while undecided) and how many of
+	// its cells are won, full or taken, level by level from the top.
+	winners []int
+	filled  []int
+}
+
+// NewRecursiveBoard returns the start of a game on boards nested depth
+// levels deep, or an error if depth is out of range.
+func NewRecursiveBoard(depth int) (*RecursiveBoard, error) {
+	if depth < 1 || depth > MAX_RECURSIVE_DEPTH {
+		return nil, fmt.Errorf("cannot nest boards %d levels deep, expected 1 to %d", depth, MAX_RECURSIVE_DEPTH)
+	}
+
+	board := &RecursiveBoard{
+		Depth:    depth,
+		Squares:  make([]int, pow9(depth)),
+		LastMove: -1,
+		Player:   1,
+		winners:  make([]int, boardIndex(depth, 0)),
+		filled:   make([]int, boardIndex(depth, 0)),
+	}
+	for i := range board.Squares {
+		board.Squares[i] = EMPTY
+	}
+	for i := range board.winners {
+		board.winners[i] = EMPTY
+	}
+
+	return board, nil
+}
+
+// pow9 returns 9 to the power of exponent.
+func pow9(exponent int) int {
+	power := 1
+	for i := 0; i < exponent; i++ {
+		power *= 9
+	}
+
+	return power
+}
+
+// boardIndex returns the index of the board at path on the given level
+// (0 for the top board) in the boards of all levels, top level first.
+func boardIndex(level, path int) int {
+	return (pow9(level)-1)/8 + path
+}
+
+// cell returns what the cell at path on the given level (1 for the cells
+// of the top board) holds: the square on the bottom level, or the winner
+// of the board above it.
+func (board *RecursiveBoard) cell(level, path int) int {
+	if level == board.Depth {
+		return board.Squares[path]
+	}
+
+	return board.winners[boardIndex(level, path)]
+}
+
+// isDead returns true if no more moves can be made in the cell at path on
+// the given level, because it is a taken square or a won or full board.
+func (board *RecursiveBoard) isDead(level, path int) bool {
+	if level == board.Depth {
+		return board.Squares[path] != EMPTY
+	}
+
+	index := boardIndex(level, path)
+	return board.winners[index] != EMPTY || board.filled[index] == 9
+}
+
+// isPlayable returns true if the board at path on the given level, and
+// every board above it, are neither won nor full.
+func (board *RecursiveBoard) isPlayable(level, path int) bool {
+	for ; level >= 0; level-- {
+		if board.isDead(level, path) {
+			return false
+		}
+		path /= 9
+	}
+
+	return true
+}
+
+// ForcedBoard returns the level and path of the board the player to move
+// has to play on (0 and 0 for the top board, meaning anywhere).
+func (board *RecursiveBoard) ForcedBoard() (int, int) {
+	if board.LastMove == -1 {
+		return 0, 0
+	}
+
+	// Drop the first cell of the last move's path, then cells at the end
+	// until the board is playable.
+	for level := board.Depth - 1; level > 0; level-- {
+		path := board.LastMove / pow9(board.Depth-1-level) % pow9(level)
+		if board.isPlayable(level, path) {
+			return level, path
+		}
+	}
+
+	return 0, 0
+}
+
+// ToMove returns the player whose turn it is.
+func (board *RecursiveBoard) ToMove() int {
+	return board.Player
+}
+
+// Moves returns the empty squares of the board the player to move is sent
+// to, leaving out those on boards which are won.
+func (board *RecursiveBoard) Moves() []int {
+	if board.IsOver() {
+		return nil
+	}
+
+	level, path := board.ForcedBoard()
+	var squares []int
+	board.appendMoves(level, path, &squares)
+	return squares
+}
+
+// appendMoves appends the empty squares below the cell at path on the
+// given level to squares, skipping boards which are won or full.
+func (board *RecursiveBoard) appendMoves(level, path int, squares *[]int) {
+	if level == board.Depth {
+		*squares = append(*squares, path)
+		return
+	}
+
+	for cell := path * 9; cell < path*9+9; cell++ {
+		if !board.isDead(level+1, cell) {
+			board.appendMoves(level+1, cell, squares)
+		}
+	}
+}
+
+// PlaySquare plays on square for the player to move and passes the turn to
+// the other player. A board won or filled by the move counts as a won or
+// full cell on the board above it, up to the top board.
+func (board *RecursiveBoard) PlaySquare(square int) {
+	board.Squares[square] = PlayerMark(board.Player)
+	board.LastMove = square
+	board.Player = Opponent(board.Player)
+
+	for level := board.Depth - 1; level >= 0; level-- {
+		path := square / pow9(board.Depth-level)
+		index := boardIndex(level, path)
+		board.filled[index] += 1
+		board.winners[index] = lineWinner(3, 3, func(x, y int) int {
+			return board.cell(level+1, path*9+x*3+y)
+		})
+
+		if !board.isDead(level, path) {
+			break
+		}
+	}
+}
+
+// Winner returns the player who has won the top board, or EMPTY.
+func (board *RecursiveBoard) Winner() int {
+	return board.winners[0]
+}
+
+// IsOver returns true if either player has won the top board, or if
+// every board below it is won or full (a tie).
+func (board *RecursiveBoard) IsOver() bool {
+	return board.isDead(0, 0)
+}
+
+// Clone returns a copy of board.
+func (board *RecursiveBoard) Clone() Game {
+	clone := *board
+	clone.Squares = append([]int(nil), board.Squares...)
+	clone.winners = append([]int(nil), board.winners...)
+	clone.filled = append([]int(nil), board.filled...)
+	return &clone
+}
+
+// squareRowColumn returns the row and column square is drawn at on
+// a grid of 3^Depth x 3^Depth squares.
+func (board *RecursiveBoard) squareRowColumn(square int) (int, int) {
+	row, column, scale := 0, 0, 1
+	for level := board.Depth; level > 0; level-- {
+		cell := square % 9
+		row += cell / 3 * scale
+		column += cell % 3 * scale
+		square /= 9
+		scale *= 3
+	}
+
+	return row, column
+}
+
+// String returns the board as a grid of 3^Depth rows of squares, with empty
+// squares drawn as '.'. Side by side, boards are separated by a space for
+// every level they are apart, one above the other by an empty line for
+// every level.
+func (board *RecursiveBoard) String() string {
+	width, _ := board.squareRowColumn(len(board.Squares) - 1)
+	width += 1
+
+	grid := make([][]byte, width)
+	for row := range grid {
+		grid[row] = make([]byte, width)
+	}
+	for square, value := range board.Squares {
+		row, column := board.squareRowColumn(square)
+		if value == EMPTY {
+			value = '.'
+		}
+		grid[row][column] = byte(value)
+	}
+
+	// separators returns how many levels of boards end before index.
+	separators := func(index int) int {
+		count := 0
+		for size := 3; size < width && index%size == 0; size *= 3 {
+			count++
+		}
+		return count
+	}
+
+	var builder strings.Builder
+	for row := range grid {
+		if row > 0 {
+			builder.WriteString(strings.Repeat("\n", separators(row)))
+		}
+		for column := range grid[row] {
+			if column > 0 {
+				builder.WriteString(strings.Repeat(" ", separators(column)))
+			}
+			builder.WriteByte(grid[row][column])
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
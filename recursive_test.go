@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+)
+
+// mustRecursiveBoard returns a new RecursiveBoard, failing the test if it cannot.
+func mustRecursiveBoard(t *testing.T, depth int) *RecursiveBoard {
+	t.Helper()
+	board, err := NewRecursiveBoard(depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return board
+}
+
+func TestRecursiveBoardPerft(t *testing.T) {
+	// With two levels, the moves are those of the standard game.
+	for depth, expected := range perftPositions[0].counts {
+		if nodes := GamePerft(mustRecursiveBoard(t, 2), depth); nodes != expected {
+			t.Errorf("levels:2: GamePerft(%d) = %d, expected %d", depth, nodes, expected)
+		}
+	}
+
+	// Every first move sends the opponent to a bottom board with 9 empty
+	// squares, except for the 9 moves a a a sending them back to a a.
+	for depth, expected := range []uint64{1, 729, 9*8 + 720*9} {
+		if nodes := GamePerft(mustRecursiveBoard(t, 3), depth); nodes != expected {
+			t.Errorf("levels:3: GamePerft(%d) = %d, expected %d", depth, nodes, expected)
+		}
+	}
+
+	// A single board is plain Tic-Tac-Toe.
+	if nodes := GamePerft(mustRecursiveBoard(t, 1), 9); nodes != 127872 {
+		t.Errorf("levels:1: GamePerft(9) = %d, expected 127872", nodes)
+	}
+}
+
+func TestRecursiveBoardForcedBoard(t *testing.T) {
+	board := mustRecursiveBoard(t, 3)
+	if level, path := board.ForcedBoard(); level != 0 || path != 0 || len(board.Moves()) != 729 {
+		t.Error("Expected the first move to be free, got", level, path, len(board.Moves()))
+	}
+
+	// X wins the bottom board 0 0 with squares 0 1 2, while O plays in the
+	// middle of the top board, and then sends X to the bottom board 0 0.
+	for _, square := range []int{0, 4*81 + 4, 1, 4*81 + 13, 2} {
+		board.PlaySquare(square)
+	}
+	if level, path := board.ForcedBoard(); level != 2 || path != 2 {
+		t.Error("Expected O to be sent to the bottom board 0 2, got", level, path)
+	}
+
+	board.PlaySquare(5 * 81)
+	if board.cell(2, 0) != PLAYER_1_CONTROLLED {
+		t.Fatal("Expected X to have won the bottom board 0 0\n" + board.String())
+	}
+
+	// The bottom board 0 0 is won, so X may play anywhere on the board 0.
+	if level, path := board.ForcedBoard(); level != 1 || path != 0 || len(board.Moves()) != 72 {
+		t.Error("Expected X to be free to play on board 0, got", level, path, len(board.Moves()))
+	}
+}
+
+func TestRecursiveBoardWinner(t *testing.T) {
+	board := mustRecursiveBoard(t, 3)
+
+	// X takes the first row of every bottom board of the first row of every
+	// board of the first row of the top board, while O plays once in the
+	// middle of the bottom boards of the middle row of the top board.
+	for i := 0; i < 26; i++ {
+		board.PlaySquare(i/9*81 + i/3%3*9 + i%3)
+		board.PlaySquare((3+i/9)*81 + i%9*9 + 4)
+	}
+
+	if board.cell(1, 0) != PLAYER_1_CONTROLLED || board.cell(1, 1) != PLAYER_1_CONTROLLED || board.cell(2, 2*9+1) != PLAYER_1_CONTROLLED {
+		t.Error("Expected X to win the boards it played on\n" + board.String())
+	}
+	if board.cell(1, 3) != EMPTY || board.cell(2, 3*9) != EMPTY {
+		t.Error("Expected O not to win any board")
+	}
+	if board.Winner() != EMPTY || board.IsOver() {
+		t.Fatal("Expected the game to be undecided\n" + board.String())
+	}
+
+	// The last square of the first row wins all three levels.
+	board.PlaySquare(2*81 + 2*9 + 2)
+	if board.Winner() != PLAYER_1_CONTROLLED || !board.IsOver() || board.Moves() != nil {
+		t.Errorf("Expected X to win the game, got %c\n%s", board.Winner(), board)
+	}
+}
+
+func TestRecursiveBoardString(t *testing.T) {
+	board := mustRecursiveBoard(t, 2)
+	board.PlaySquare(5*9 + 7)
+
+	expected := "... ... ...\n... ... ...\n... ... ...\n\n... ... ...\n... ... ...\n... ... .X.\n\n... ... ...\n... ... ...\n... ... ...\n"
+	if board.String() != expected {
+		t.Errorf("Expected the board\n%s\ngot\n%s", expected, board)
+	}
+}
+
+func TestNewVariant(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		squares int
+	}{{"levels:3", 729}, {"levels:1", 9}, {"3x3:3", 81}, {"5x5:4", 625}} {
+		game, err := NewVariant(test.name)
+		if err != nil || len(game.Moves()) != test.squares {
+			t.Errorf("Expected %s to have %d moves at the start, got %v", test.name, test.squares, err)
+		}
+	}
+
+	for _, name := range []string{"levels:5", "levels:", "levels:x", "3x3"} {
+		if _, err := NewVariant(name); err == nil {
+			t.Errorf("Expected the variant %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules are the rule variants a game is played with, any of the RULES_*
+// flags combined. The zero Rules are the standard game.
+type Rules uint8
+
+const (
+	// RULES_MISERE makes completing a line of boards lose the game.
+	RULES_MISERE Rules = 1 << iota
+	// RULES_MOST_BOARDS ignores lines of boards: once no moves are left,
+	// whoever has won the most boards wins the game.
+	RULES_MOST_BOARDS
+	// RULES_FREE_MOVES drops the forced board rule, so that players may
+	// always play on any board which is neither won nor full.
+	RULES_FREE_MOVES
+)
+
+// ruleNames holds the name of every rule variant, as ParseRules reads it.
+var ruleNames = []struct {
+	name  string
+	rules Rules
+}{
+	{"misere", RULES_MISERE},
+	{"most-boards", RULES_MOST_BOARDS},
+	{"free", RULES_FREE_MOVES},
+}
+
+// DefaultRules are the rules NewGame, ReadGameState and ParsePosition
+// give the games they create, unless told otherwise.
+var DefaultRules Rules
+
+// ParseRules reads rule variants separated by commas, e.g. "misere,free",
+// or "standard" for none.
+func ParseRules(names string) (Rules, error) {
+	var rules Rules
+	for _, name := range strings.Split(names, ",") {
+		if name == "standard" || name == "" {
+			continue
+		}
+
+		found := false
+		for _, variant := range ruleNames {
+			if variant.name == name {
+				rules |= variant.rules
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown rules %q, expected standard, misere, most-boards or free", name)
+		}
+	}
+
+	if rules&RULES_MISERE != 0 && rules&RULES_MOST_BOARDS != 0 {
+		return 0, fmt.Errorf("misere and most-boards rules cannot be combined")
+	}
+
+	return rules, nil
+}
+
+// String returns the names of the rule variants, as ParseRules reads them.
+func (rules Rules) String() string {
+	var names []string
+	for _, variant := range ruleNames {
+		if rules&variant.rules != 0 {
+			names = append(names, variant.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "standard"
+	}
+	return strings.Join(names, ",")
+}
+
+// Winner returns the player who has won the game on board under the rules
+// (PLAYER_1_CONTROLLED or PLAYER_2_CONTROLLED), or EMPTY while the game
+// goes on or if it is a tie.
+func (rules Rules) Winner(board *UltimateBoard) int {
+	status := NewBoardStatus(board)
+	return rules.StatusWinner(&status)
+}
+
+// StatusWinner returns the same as Winner, for the board status is the
+// status of, without checking the board again.
+func (rules Rules) StatusWinner(status *BoardStatus) int {
+	switch {
+	case rules&RULES_MISERE != 0:
+		switch status.Winner {
+		case PLAYER_1_CONTROLLED:
+			return PLAYER_2_CONTROLLED
+		case PLAYER_2_CONTROLLED:
+			return PLAYER_1_CONTROLLED
+		}
+		return EMPTY
+
+	case rules&RULES_MOST_BOARDS != 0:
+		boards := map[int]int{}
+		for x := 0; x < 3; x++ {
+			for y := 0; y < 3; y++ {
+				if status.IsOpen(x, y) {
+					return EMPTY
+				}
+				boards[status.Boards[x][y]] += 1
+			}
+		}
+		switch {
+		case boards[PLAYER_1_CONTROLLED] > boards[PLAYER_2_CONTROLLED]:
+			return PLAYER_1_CONTROLLED
+		case boards[PLAYER_2_CONTROLLED] > boards[PLAYER_1_CONTROLLED]:
+			return PLAYER_2_CONTROLLED
+		}
+		return EMPTY
+	}
+
+	return status.Winner
+}
+
+// readRulesLine checks the "rules" line of an opening book or puzzle file,
+// split into fields. The positions in the file are read under DefaultRules,
+// so they must have been written under the same rules.
+func readRulesLine(fields []string) error {
+	if len(fields) != 2 || fields[0] != "rules" {
+		return fmt.Errorf("expected the rules, got %q", strings.Join(fields, " "))
+	}
+
+	rules, err := ParseRules(fields[1])
+	if err != nil {
+		return err
+	}
+	if rules != DefaultRules {
+		return fmt.Errorf("written for the %s rules, but playing under the %s rules", rules, DefaultRules)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// rulesPerftCounts holds the reference Perft counts of the perftPositions
+// under every ruleset, for depth 0, 1, 2... The misère rules end the game
+// on the same moves as the standard rules, so their counts are the same.
+var rulesPerftCounts = []struct {
+	rules  Rules
+	counts [][]uint64 // For each of the perftPositions
+}{
+	{RULES_MISERE, [][]uint64{{1, 81, 720, 6336}, {1, 8, 72, 624}, {1, 7, 90, 1506, 24227}}},
+	{RULES_MOST_BOARDS, [][]uint64{{1, 81, 720, 6336}, {1, 8, 72, 624}, {1, 7, 90, 1545, 24981}}},
+	{RULES_FREE_MOVES, [][]uint64{{1, 81, 6480, 511920}, {1, 80, 6320, 492960}, {1, 46, 2064, 88608}}},
+	{RULES_MOST_BOARDS | RULES_FREE_MOVES, [][]uint64{{1, 81, 6480, 511920}, {1, 80, 6320, 492960}, {1, 46, 2064, 90318}}},
+}
+
+func TestRulesPerft(t *testing.T) {
+	for _, test := range rulesPerftCounts {
+		for i, position := range perftPositions {
+			state, err := ReadGameState(strings.NewReader(position.position))
+			if err != nil {
+				t.Fatal("Failed to read the", position.name, "position:", err)
+			}
+			state.Rules = test.rules
+
+			for depth, expected := range test.counts[i] {
+				if nodes := Perft(state, depth); nodes != expected {
+					t.Errorf("%s, %s: Perft(%d) = %d, expected %d", test.rules, position.name, depth, nodes, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	for names, expected := range map[string]Rules{
+		"standard":         0,
+		"":                 0,
+		"misere":           RULES_MISERE,
+		"free,most-boards": RULES_MOST_BOARDS | RULES_FREE_MOVES,
+	} {
+		rules, err := ParseRules(names)
+		if err != nil || rules != expected {
+			t.Errorf("Expected %q to be read as %s, got %s (%v)", names, expected, rules, err)
+		}
+
+		if again, _ := ParseRules(rules.String()); again != rules {
+			t.Errorf("%s does not round trip through its name %q", rules, rules.String())
+		}
+	}
+
+	for _, names := range []string{"misere,most-boards", "suicide"} {
+		if _, err := ParseRules(names); err == nil {
+			t.Errorf("Expected %q to be rejected", names)
+		}
+	}
+}
+
+func TestRulesWinner(t *testing.T) {
+	// X has won five boards, on a diagonal among others, and O the other four.
+	won := map[int]TictactoeBoard{
+		PLAYER_1_CONTROLLED: {{PLAYER_1_CONTROLLED, PLAYER_1_CONTROLLED, PLAYER_1_CONTROLLED}, {EMPTY, EMPTY, EMPTY}, {EMPTY, EMPTY, EMPTY}},
+		PLAYER_2_CONTROLLED: {{PLAYER_2_CONTROLLED, PLAYER_2_CONTROLLED, PLAYER_2_CONTROLLED}, {EMPTY, EMPTY, EMPTY}, {EMPTY, EMPTY, EMPTY}},
+	}
+	var board UltimateBoard
+	for i := 0; i < 9; i++ {
+		if i%2 == 0 {
+			board[i/3][i%3] = won[PLAYER_1_CONTROLLED]
+		} else {
+			board[i/3][i%3] = won[PLAYER_2_CONTROLLED]
+		}
+	}
+
+	for rules, expected := range map[Rules]int{
+		0:                 PLAYER_1_CONTROLLED,
+		RULES_FREE_MOVES:  PLAYER_1_CONTROLLED,
+		RULES_MISERE:      PLAYER_2_CONTROLLED,
+		RULES_MOST_BOARDS: PLAYER_1_CONTROLLED,
+	} {
+		if winner := rules.Winner(&board); winner != expected {
+			t.Errorf("%s: expected %c to win, got %c", rules, expected, winner)
+		}
+	}
+
+	// Under the most-boards rules, the game goes on while there are moves.
+	board[1][1].Clear()
+	state := GameState{Board: board, LastMove: Move{-1, -1, -1, -1}, Player: 1, Rules: RULES_MOST_BOARDS}
+	if state.Winner() != EMPTY || state.IsOver() || len(state.ValidMoves()) != 9 {
+		t.Error("Expected the game to go on with one board left")
+	}
+	state.Rules = 0
+	if state.Winner() != EMPTY || state.IsOver() {
+		t.Error("Expected no line of boards without the middle board")
+	}
+}
+
+func TestFreeMoves(t *testing.T) {
+	state := NewGame()
+	state.Rules = RULES_FREE_MOVES
+	state.Play(&Move{1, 1, 0, 2})
+
+	if _, _, forced := state.ForcedBoard(); forced || len(state.ValidMoves()) != 80 {
+		t.Error("Expected O to be free to play anywhere, got", len(state.ValidMoves()), "moves")
+	}
+	if !strings.HasPrefix(FormatPosition(state), "O -1 -1 ") {
+		t.Error("Expected the position to send O anywhere, got", FormatPosition(state))
+	}
+}
+
+func TestBotsUnderMisereRules(t *testing.T) {
+	// X is sent to (2,2), where 2 2 2 2 wins the board and the game, which
+	// under the misère rules loses it instead.
+	position := "X\n2 2\nX--XOXO--\n-X-XOO---\n--XOXX---\n---XXX-O-\n-O-OO----\n---------\n---O--X--\n-------X-\nO-O------\n"
+	config := DefaultSearchConfig
+	config.MaxPlayouts = 2000
+	config.MaxDepth = 2
+	config.SolverThreshold = 0
+
+	for _, name := range []string{"uct", "rave", "heavy", "alphabeta", "puct"} {
+		for _, rules := range []Rules{0, RULES_MISERE} {
+			state, _ := ReadGameState(strings.NewReader(position))
+			state.Rules = rules
+
+			move := *Analysts[name](config)(state).BestMove()
+			if wins := move == (Move{2, 2, 2, 2}); wins != (rules == 0) {
+				t.Errorf("%s under the %s rules played %v", name, rules, move)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// TrainingSample is a position from a recorded game, with what the
+// network should learn to predict for it.
+type TrainingSample struct {
+	State GameState `json:"state"`
+
+	// Policy holds the share of the search's visits that went to each
+	// move, indexed by moveIndex. Only legal moves may have a share.
+	Policy [NETWORK_MOVES]float64 `json:"policy"`
+
+	// Value is the result of the game for the player to
+	// move: 1 for a win, 0 for a tie and -1 for a loss.
+	Value float64 `json:"value"`
+}
+
+// moves returns the indices of the legal moves in the sample's
+// position, and the share of the visits each of them got.
+func (sample *TrainingSample) moves() ([]int, []float64) {
+	validMoves := sample.State.ValidMoves()
+	moves := make([]int, len(validMoves))
+	targets := make([]float64, len(validMoves))
+	for i, move := range validMoves {
+		moves[i] = moveIndex(move)
+		targets[i] = sample.Policy[moves[i]]
+	}
+
+	return moves, targets
+}
+
+// SAMPLES_MAGIC starts every binary samples file, followed by a version byte.
+const (
+	SAMPLES_MAGIC   = "UTSP"
+	SAMPLES_VERSION = 2
+)
+
+// A SampleWriter writes TrainingSamples as JSON, one sample per line, or in a
+// compact binary format. A binary file starts with SAMPLES_MAGIC, a version
+// byte and a byte with the Rules of its positions, DefaultRules. Every sample is then 81 bytes with the squares row by row, as in
+// HackerRank's format (0 for empty, 1 for X and 2 for O), a byte for the
+// player to move, 4 signed bytes for the last move and one for the value, a
+// byte counting the moves with a share of the visits and for each of those
+// moves its index and its share, as a little-endian uint16 out of 65535.
+type SampleWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder // nil when writing binary samples
+	rules   Rules         // The rules of every binary sample
+}
+
+// NewSampleWriter returns a SampleWriter writing to w in format,
+// "jsonl" or "binary". Flush must be called when done.
+func NewSampleWriter(w io.Writer, format string) (*SampleWriter, error) {
+	writer := &SampleWriter{buffer: bufio.NewWriter(w), rules: DefaultRules}
+	switch format {
+	case "jsonl":
+		writer.encoder = json.NewEncoder(writer.buffer)
+	case "binary":
+		writer.buffer.WriteString(SAMPLES_MAGIC)
+		writer.buffer.WriteByte(SAMPLES_VERSION)
+		writer.buffer.WriteByte(byte(writer.rules))
+	default:
+		return nil, fmt.Errorf("unknown samples format %q, expected jsonl or binary", format)
+	}
+
+	return writer, nil
+}
+
+// Write writes sample. Binary samples must be played under DefaultRules,
+// the rules written at the start of the file.
+func (writer *SampleWriter) Write(sample *TrainingSample) error {
+	if writer.encoder != nil {
+		return writer.encoder.Encode(sample)
+	}
+	if sample.State.Rules != writer.rules {
+		return fmt.Errorf("cannot write a sample played under the %s rules with samples played under the %s rules", sample.State.Rules, writer.rules)
+	}
+
+	var record []byte
+	for row := 0; row < 9; row++ {
+		for column := 0; column < 9; column++ {
+			var square byte
+			switch sample.State.Board[row/3][column/3][row%3][column%3] {
+			case PLAYER_1_CONTROLLED:
+				square = 1
+			case PLAYER_2_CONTROLLED:
+				square = 2
+			}
+			record = append(record, square)
+		}
+	}
+
+	lastMove := sample.State.LastMove
+	record = append(record, byte(sample.State.Player), byte(int8(lastMove.BoardX)), byte(int8(lastMove.BoardY)),
+		byte(int8(lastMove.TileX)), byte(int8(lastMove.TileY)), byte(int8(sample.Value)))
+
+	countAt := len(record)
+	record = append(record, 0)
+	for i, share := range sample.Policy {
+		if share > 0 {
+			record[countAt] += 1
+			record = append(record, byte(i))
+			record = binary.LittleEndian.AppendUint16(record, uint16(math.Round(share*math.MaxUint16)))
+		}
+	}
+
+	_, err := writer.buffer.Write(record)
+	return err
+}
+
+// Flush writes any buffered samples to the underlying writer.
+func (writer *SampleWriter) Flush() error {
+	return writer.buffer.Flush()
+}
+
+// WriteTrainingSamples writes samples to w as JSON, one sample per line.
+func WriteTrainingSamples(w io.Writer, samples []TrainingSample) error {
+	writer, _ := NewSampleWriter(w, "jsonl")
+	for i := range samples {
+		if err := writer.Write(&samples[i]); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// ReadTrainingSamples reads samples written by a SampleWriter, in either format.
+func ReadTrainingSamples(r io.Reader) ([]TrainingSample, error) {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(len(SAMPLES_MAGIC)); string(magic) == SAMPLES_MAGIC {
+		return readBinarySamples(reader)
+	}
+
+	var samples []TrainingSample
+	decoder := json.NewDecoder(reader)
+	for {
+		var sample TrainingSample
+		err := decoder.Decode(&sample)
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %v", len(samples)+1, err)
+		}
+
+		samples = append(samples, sample)
+	}
+}
+
+// readBinarySamples reads samples in the binary format described at
+// SampleWriter, which must have been written under DefaultRules.
+func readBinarySamples(reader *bufio.Reader) ([]TrainingSample, error) {
+	header := make([]byte, len(SAMPLES_MAGIC)+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if header[len(SAMPLES_MAGIC)] != SAMPLES_VERSION {
+		return nil, fmt.Errorf("expected version %d samples, got version %d", SAMPLES_VERSION, header[len(SAMPLES_MAGIC)])
+	}
+	if rules := Rules(header[len(SAMPLES_MAGIC)+1]); rules != DefaultRules {
+		return nil, fmt.Errorf("samples played under the %s rules, but playing under the %s rules", rules, DefaultRules)
+	}
+
+	var samples []TrainingSample
+	record := make([]byte, 81+7)
+	for {
+		if _, err := io.ReadFull(reader, record); err == io.EOF {
+			return samples, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("sample %d: %v", len(samples)+1, err)
+		}
+
+		sample := TrainingSample{State: GameState{Rules: DefaultRules}}
+		for i, square := range record[:81] {
+			row, column := i/9, i%9
+			switch square {
+			case 0:
+				sample.State.Board[row/3][column/3][row%3][column%3] = EMPTY
+			case 1:
+				sample.State.Board[row/3][column/3][row%3][column%3] = PLAYER_1_CONTROLLED
+			case 2:
+				sample.State.Board[row/3][column/3][row%3][column%3] = PLAYER_2_CONTROLLED
+			default:
+				return nil, fmt.Errorf("sample %d: unknown square %d", len(samples)+1, square)
+			}
+		}
+
+		sample.State.Player = int(record[81])
+		if sample.State.Player != 1 && sample.State.Player != 2 {
+			return nil, fmt.Errorf("sample %d: unknown player %d", len(samples)+1, record[81])
+		}
+		sample.State.LastMove = Move{int(int8(record[82])), int(int8(record[83])), int(int8(record[84])), int(int8(record[85]))}
+		sample.Value = float64(int8(record[86]))
+
+		shares := make([]byte, 3*int(record[87]))
+		if _, err := io.ReadFull(reader, shares); err != nil {
+			return nil, fmt.Errorf("sample %d: %v", len(samples)+1, err)
+		}
+		for i := 0; i < len(shares); i += 3 {
+			if int(shares[i]) >= NETWORK_MOVES {
+				return nil, fmt.Errorf("sample %d: unknown move %d", len(samples)+1, shares[i])
+			}
+			sample.Policy[shares[i]] = float64(binary.LittleEndian.Uint16(shares[i+1:])) / math.MaxUint16
+		}
+
+		samples = append(samples, sample)
+	}
+}
+
+// LoadTrainingSamples reads the samples in the file at path.
+func LoadTrainingSamples(path string) ([]TrainingSample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadTrainingSamples(file)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrainingSamplesRoundTrip(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{2, 1, 0, 2})
+
+	sample := TrainingSample{State: *state, Value: -1}
+	sample.Policy[moveIndex(&Move{0, 2, 1, 1})] = 0.75
+	sample.Policy[moveIndex(&Move{0, 2, 0, 0})] = 0.25
+
+	var buffer bytes.Buffer
+	if err := WriteTrainingSamples(&buffer, []TrainingSample{sample, sample}); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(buffer.String(), "\n"); lines != 2 {
+		t.Error("Expected one line per sample, got", lines, "lines")
+	}
+
+	samples, err := ReadTrainingSamples(&buffer)
+	if err != nil || len(samples) != 2 || samples[1] != sample {
+		t.Fatal("Expected to read back the samples, got", samples, err)
+	}
+
+	moves, targets := samples[0].moves()
+	var total float64
+	for i := range moves {
+		total += targets[i]
+	}
+	if len(moves) != 9 || total != 1 {
+		t.Error("Expected the visits to be spread over the 9 moves on board (0,2), got", moves, targets)
+	}
+}
+
+func TestBinaryTrainingSamples(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{2, 1, 0, 2})
+	state.Play(&Move{0, 2, 1, 1})
+
+	sample := TrainingSample{State: *state, Value: -1}
+	sample.Policy[moveIndex(&Move{1, 1, 2, 2})] = 1
+
+	var buffer bytes.Buffer
+	writer, err := NewSampleWriter(&buffer, "binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(&sample)
+	writer.Write(&sample)
+	writer.Flush()
+
+	if size := buffer.Len(); size != len(SAMPLES_MAGIC)+2+2*(81+7+3) {
+		t.Error("Expected 2 samples with one move each to take", len(SAMPLES_MAGIC)+2+2*(81+7+3), "bytes, got", size)
+	}
+
+	samples, err := ReadTrainingSamples(bytes.NewReader(buffer.Bytes()))
+	if err != nil || len(samples) != 2 || samples[1] != sample {
+		t.Fatal("Expected to read back the samples, got", samples, err)
+	}
+
+	if _, err := ReadTrainingSamples(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1])); err == nil {
+		t.Error("Expected an error reading a truncated sample")
+	}
+
+	misere := append([]byte(nil), buffer.Bytes()...)
+	misere[len(SAMPLES_MAGIC)+1] = byte(RULES_MISERE)
+	if _, err := ReadTrainingSamples(bytes.NewReader(misere)); err == nil {
+		t.Error("Expected an error reading samples played under other rules")
+	}
+
+	badPlayer := append([]byte(nil), buffer.Bytes()...)
+	badPlayer[len(SAMPLES_MAGIC)+2+81] = 3
+	if _, err := ReadTrainingSamples(bytes.NewReader(badPlayer)); err == nil {
+		t.Error("Expected an error reading a sample with player 3 to move")
+	}
+
+	sample.State.Rules = RULES_FREE_MOVES
+	if err := writer.Write(&sample); err == nil {
+		t.Error("Expected an error writing a sample played under other rules")
+	}
+}
+
+func TestReadTrainingSamplesError(t *testing.T) {
+	if _, err := ReadTrainingSamples(strings.NewReader("{\"value\": 1}\nnot json\n")); err == nil {
+		t.Error("Expected an error reading a broken sample")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A SelectionStrategy decides which move a search plays in the end,
+// based on the statistics it gathered for each of the moves.
+type SelectionStrategy int
+
+const (
+	SELECT_DEFAULT                SelectionStrategy = iota // Legacy for MonteCarloBot, most visits for the tree search
+	SELECT_MOST_VISITS                                     // The move searched the most
+	SELECT_HIGHEST_MEAN                                    // The move with the best average result
+	SELECT_ROBUST_MAX                                      // The move doing best on both visits and average result
+	SELECT_LOWER_CONFIDENCE_BOUND                          // The move with the best pessimistic estimate of its result
+	SELECT_LEGACY                                          // MonteCarloBot's original (weighted wins - loss weight * weighted losses) / visits
+)
+
+// LCB_Z is how many standard errors below the average result
+// SELECT_LOWER_CONFIDENCE_BOUND puts its estimate (95% one-sided).
+const LCB_Z = 1.645
+
+// selectionStrategyNames maps the names used on the command line to strategies.
+var selectionStrategyNames = map[string]SelectionStrategy{
+	"default":     SELECT_DEFAULT,
+	"most-visits": SELECT_MOST_VISITS,
+	"mean":        SELECT_HIGHEST_MEAN,
+	"robust-max":  SELECT_ROBUST_MAX,
+	"lcb":         SELECT_LOWER_CONFIDENCE_BOUND,
+	"legacy":      SELECT_LEGACY,
+}
+
+// ParseSelectionStrategy returns the SelectionStrategy called name.
+func ParseSelectionStrategy(name string) (SelectionStrategy, error) {
+	strategy, ok := selectionStrategyNames[name]
+	if !ok {
+		names := make([]string, 0, len(selectionStrategyNames))
+		for name := range selectionStrategyNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return 0, fmt.Errorf("unknown selection strategy %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	return strategy, nil
+}
+
+// moveStats holds what a search found out about a single move, from the
+// point of view of the player making it. The weighted results are divided
+// by the number of moves it took until the game ended.
+type moveStats struct {
+	visits         float64
+	wins           float64
+	draws          float64
+	weightedWins   float64
+	weightedLosses float64
+}
+
+// losses returns the number of games lost after the move.
+func (stats *moveStats) losses() float64 {
+	return stats.visits - stats.wins - stats.draws
+}
+
+// mean returns the average result after the move, where
+// a win counts as 1, a tie as 0.5 and a loss as 0.
+func (stats *moveStats) mean() float64 {
+	return (stats.wins + 0.5*stats.draws) / stats.visits
+}
+
+// lowerConfidenceBound returns the average result, minus LCB_Z
+// standard errors, so that rarely visited moves are distrusted.
+func (stats *moveStats) lowerConfidenceBound() float64 {
+	mean := stats.mean()
+	variance := (stats.wins+0.25*stats.draws)/stats.visits - mean*mean
+	return mean - LCB_Z*math.Sqrt(math.Max(variance, 0)/stats.visits)
+}
+
+// legacyScore returns MonteCarloBot's original score for the move. Moves
+// that win quickly are favored, while moves that lose quickly are strongly
+// disfavored, or with lengthWeighting off just moves that lose often.
+func (stats *moveStats) legacyScore(lossWeight float64, lengthWeighting bool) float64 {
+	if !lengthWeighting {
+		return (stats.wins - stats.losses()*lossWeight) / stats.visits
+	}
+
+	return (stats.weightedWins - stats.weightedLosses*lossWeight) / stats.visits
+}
+
+// rankMoves returns the score of every move under the strategy configured in
+// config, and the order in which the strategy prefers them, best first.
+// Moves that have not been visited at all always come last.
+func rankMoves(stats []moveStats, config *SearchConfig) ([]float64, []int) {
+	var maxVisits, maxMean float64
+	for i := range stats {
+		if stats[i].visits > 0 {
+			maxVisits = math.Max(maxVisits, stats[i].visits)
+			maxMean = math.Max(maxMean, stats[i].mean())
+		}
+	}
+
+	scores := make([]float64, len(stats))
+	order := make([]int, len(stats))
+	for i := range stats {
+		order[i] = i
+		if stats[i].visits == 0 {
+			scores[i] = math.Inf(-1)
+			continue
+		}
+
+		switch config.Selection {
+		case SELECT_DEFAULT, SELECT_MOST_VISITS:
+			scores[i] = stats[i].visits
+		case SELECT_HIGHEST_MEAN:
+			scores[i] = stats[i].mean()
+		case SELECT_ROBUST_MAX:
+			// The move with both the most visits and the best average result
+			// scores 1. Otherwise the move with the best worse side wins.
+			scores[i] = stats[i].visits / maxVisits
+			if maxMean > 0 {
+				scores[i] = math.Min(scores[i], stats[i].mean()/maxMean)
+			}
+		case SELECT_LOWER_CONFIDENCE_BOUND:
+			scores[i] = stats[i].lowerConfidenceBound()
+		case SELECT_LEGACY:
+			scores[i] = stats[i].legacyScore(config.LegacyLossWeight, config.LegacyLengthWeighting)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	return scores, order
+}
+
+// rankCandidates returns the moves with their statistics as CandidateMoves,
+// ranked and scored by the strategy configured in config.
+func rankCandidates(moves []*Move, stats []moveStats, config *SearchConfig) []CandidateMove {
+	scores, order := rankMoves(stats, config)
+
+	candidates := make([]CandidateMove, 0, len(moves))
+	for _, i := range order {
+		candidates = append(candidates, CandidateMove{
+			Move:     *moves[i],
+			Visits:   int(stats[i].visits),
+			WinRate:  stats[i].wins / stats[i].visits,
+			DrawRate: stats[i].draws / stats[i].visits,
+			LossRate: stats[i].losses() / stats[i].visits,
+			Score:    scores[i],
+		})
+	}
+
+	return candidates
+}
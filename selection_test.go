@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSelectionStrategy(t *testing.T) {
+	for name, expected := range selectionStrategyNames {
+		if strategy, err := ParseSelectionStrategy(name); err != nil || strategy != expected {
+			t.Error("Expected", name, "to parse as strategy", expected, "got", strategy, err)
+		}
+	}
+
+	if _, err := ParseSelectionStrategy("best"); err == nil {
+		t.Error("Expected an unknown strategy to be rejected")
+	}
+}
+
+func TestRankMoves(t *testing.T) {
+	stats := []moveStats{
+		// Searched the most, with a mediocre result.
+		{visits: 100, wins: 50, draws: 10, weightedWins: 5, weightedLosses: 4},
+		// The best average result, from very few playouts.
+		{visits: 4, wins: 3, draws: 1, weightedWins: 1},
+		// A good result with a fair number of playouts.
+		{visits: 80, wins: 64, weightedWins: 2, weightedLosses: 0.5},
+		// Never searched.
+		{},
+	}
+
+	tests := []struct {
+		strategy SelectionStrategy
+		best     int
+	}{
+		{SELECT_DEFAULT, 0},
+		{SELECT_MOST_VISITS, 0},
+		{SELECT_HIGHEST_MEAN, 1},
+		{SELECT_ROBUST_MAX, 2},
+		{SELECT_LOWER_CONFIDENCE_BOUND, 2},
+		{SELECT_LEGACY, 1},
+	}
+
+	for _, test := range tests {
+		config := SearchConfig{Selection: test.strategy, LegacyLossWeight: 2, LegacyLengthWeighting: true}
+		_, order := rankMoves(stats, &config)
+		if order[0] != test.best {
+			t.Error("Expected strategy", test.strategy, "to prefer move", test.best, "got the order", order)
+		}
+
+		if order[len(order)-1] != 3 {
+			t.Error("Expected strategy", test.strategy, "to rank the unvisited move last, got the order", order)
+		}
+	}
+}
+
+func TestLegacyScore(t *testing.T) {
+	stats := moveStats{visits: 10, wins: 5, draws: 1, weightedWins: 1, weightedLosses: 0.5}
+
+	if score := stats.legacyScore(2, true); score != 0 {
+		t.Error("Expected the length weighted legacy score to be (1 - 2*0.5)/10 = 0, got", score)
+	}
+
+	if score := stats.legacyScore(1, false); score != 0.1 {
+		t.Error("Expected the unweighted legacy score to be (5 - 4)/10 = 0.1, got", score)
+	}
+}
+
+func TestSearchTreeSelection(t *testing.T) {
+	for name, strategy := range selectionStrategyNames {
+		config := testSearchConfig
+		config.Selection = strategy
+		config.LegacyLossWeight = DefaultSearchConfig.LegacyLossWeight
+
+		tree := NewSearchTree(NewGame(), config)
+		tree.Search(nil)
+
+		analysis := tree.Analysis()
+		if move := tree.BestMove(); *move != analysis.Candidates[0].Move || *move != analysis.PrincipalVariation[0] {
+			t.Error("Strategy", name, "played", *move, "but ranked", analysis.Candidates[0].Move, "first")
+		}
+
+		for i := 1; i < len(analysis.Candidates); i++ {
+			if analysis.Candidates[i].Score > analysis.Candidates[i-1].Score {
+				t.Error("Strategy", name, "ranked a move with score", analysis.Candidates[i].Score, "below one with score", analysis.Candidates[i-1].Score)
+				break
+			}
+		}
+	}
+}
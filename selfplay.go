@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// visitDistribution returns the share of the visits each candidate in
+// analysis got, by moveIndex. Searches without visits, like the solver,
+// give all of it to their best move.
+func visitDistribution(analysis *Analysis) [NETWORK_MOVES]float64 {
+	var policy [NETWORK_MOVES]float64
+	var visits int
+	for _, candidate := range analysis.Candidates {
+		visits += candidate.Visits
+	}
+
+	if visits == 0 {
+		if best := analysis.BestMove(); best != nil {
+			policy[moveIndex(best)] = 1
+		}
+		return policy
+	}
+
+	for _, candidate := range analysis.Candidates {
+		policy[moveIndex(&candidate.Move)] = float64(candidate.Visits) / float64(visits)
+	}
+
+	return policy
+}
+
+// augment returns the sample transformed by every Symmetry, itself first,
+// leaving out transformations which give a position already returned.
+func (sample *TrainingSample) augment() []TrainingSample {
+	samples := make([]TrainingSample, 0, NUM_SYMMETRIES)
+	for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+		transformed := TrainingSample{State: symmetry.State(&sample.State), Value: sample.Value}
+		duplicate := false
+		for i := range samples {
+			duplicate = duplicate || samples[i].State == transformed.State
+		}
+		if duplicate {
+			continue
+		}
+
+		for i, share := range sample.Policy {
+			move := symmetry.Move(Move{i / 27, i / 9 % 3, i / 3 % 3, i % 3})
+			transformed.Policy[moveIndex(&move)] = share
+		}
+		samples = append(samples, transformed)
+	}
+
+	return samples
+}
+
+// selfPlayGame plays a game between player1 (X) and player2 (O), with
+// RANDOM_OPENING_MOVES random moves first, and returns a sample for every
+// position after those.
+func selfPlayGame(player1, player2 Analyst, random *rand.Rand) []TrainingSample {
+	state := NewGame()
+	var samples []TrainingSample
+	for moves := 0; !state.IsOver(); moves++ {
+		if moves < RANDOM_OPENING_MOVES {
+			validMoves := state.ValidMoves()
+			state.Play(validMoves[random.Intn(len(validMoves))])
+			continue
+		}
+
+		analyst := player1
+		if state.Player == 2 {
+			analyst = player2
+		}
+
+		analysis := analyst(state)
+		samples = append(samples, TrainingSample{State: *state, Policy: visitDistribution(analysis)})
+		state.Play(analysis.BestMove())
+	}
+
+	winner := state.Winner()
+	for i := range samples {
+		switch winner {
+		case EMPTY:
+		case PlayerMark(samples[i].State.Player):
+			samples[i].Value = 1
+		default:
+			samples[i].Value = -1
+		}
+	}
+
+	return samples
+}
+
+// SelfPlay plays games games between the two analysts, taking turns at making
+// the first move, spread over all CPUs. The samples of every finished game
+// are passed to record, by one goroutine at a time. With augment, every
+// sample is recorded under each Symmetry. It stops at the first error record
+// returns, and returns it.
+func SelfPlay(analysts [2]Analyst, games int, augment bool, record func([]TrainingSample) error) error {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var recordErr error
+
+	gameNumbers := make(chan int)
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		random := rand.New(rand.NewSource(rand.Int63()))
+		go func() {
+			defer wg.Done()
+			for game := range gameNumbers {
+				var samples []TrainingSample
+				if game%2 == 0 {
+					samples = selfPlayGame(analysts[0], analysts[1], random)
+				} else {
+					samples = selfPlayGame(analysts[1], analysts[0], random)
+				}
+
+				if augment {
+					var augmented []TrainingSample
+					for i := range samples {
+						augmented = append(augmented, samples[i].augment()...)
+					}
+					samples = augmented
+				}
+
+				lock.Lock()
+				if recordErr == nil {
+					recordErr = record(samples)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for game := 0; game < games; game++ {
+		lock.Lock()
+		failed := recordErr != nil
+		lock.Unlock()
+		if failed {
+			break
+		}
+
+		gameNumbers <- game
+	}
+	close(gameNumbers)
+	wg.Wait()
+
+	return recordErr
+}
+
+// RunSelfPlay plays games games between the two bots named in names,
+// separated by a comma, and writes the samples to the file at path
+// in format ("jsonl" or "binary"). It returns a summary of what it wrote.
+func RunSelfPlay(names string, config SearchConfig, games int, path, format string, augment bool) (string, error) {
+	analysts, err := newAnalysts(strings.Split(names, ","), config)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer, err := NewSampleWriter(file, format)
+	if err != nil {
+		return "", err
+	}
+
+	written := 0
+	err = SelfPlay(analysts, games, augment, func(samples []TrainingSample) error {
+		for i := range samples {
+			if err := writer.Write(&samples[i]); err != nil {
+				return err
+			}
+		}
+		written += len(samples)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("wrote %d samples from %d games to %s\n", written, games, path), file.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVisitDistribution(t *testing.T) {
+	analysis := &Analysis{Candidates: []CandidateMove{
+		{Move: Move{1, 1, 0, 0}, Visits: 3},
+		{Move: Move{1, 1, 2, 2}, Visits: 1},
+	}}
+
+	policy := visitDistribution(analysis)
+	if policy[moveIndex(&Move{1, 1, 0, 0})] != 0.75 || policy[moveIndex(&Move{1, 1, 2, 2})] != 0.25 {
+		t.Error("Expected the visits to be split 3 to 1, got", policy)
+	}
+
+	policy = visitDistribution(moveAnalysis(&Move{0, 2, 1, 0}))
+	if policy[moveIndex(&Move{0, 2, 1, 0})] != 1 {
+		t.Error("Expected an analysis without visits to give its best move everything, got", policy)
+	}
+}
+
+func TestTrainingSampleAugment(t *testing.T) {
+	state := NewGame()
+	state.Play(&Move{0, 1, 2, 2})
+	state.Play(&Move{2, 2, 1, 0})
+
+	sample := TrainingSample{State: *state, Value: 1}
+	sample.Policy[moveIndex(&Move{1, 0, 0, 0})] = 0.5
+	sample.Policy[moveIndex(&Move{1, 0, 2, 1})] = 0.5
+
+	samples := sample.augment()
+	if len(samples) != NUM_SYMMETRIES || samples[0] != sample {
+		t.Fatal("Expected the sample itself followed by its 7 transformations")
+	}
+
+	// Only the center square has been played, every symmetry gives the same position.
+	symmetric := NewGame()
+	symmetric.Play(&Move{1, 1, 1, 1})
+	if samples := (&TrainingSample{State: *symmetric}).augment(); len(samples) != 1 {
+		t.Error("Expected a symmetric position not to be repeated, got", len(samples), "samples")
+	}
+
+	for _, augmented := range samples {
+		// The visits are still spread over legal moves only.
+		var total float64
+		for _, move := range augmented.State.ValidMoves() {
+			total += augmented.Policy[moveIndex(move)]
+		}
+
+		if math.Abs(total-1) > 1e-9 || augmented.Value != 1 {
+			t.Error("Expected the whole policy on legal moves and the value kept, got", total, augmented.Value)
+		}
+	}
+}
+
+func TestSelfPlay(t *testing.T) {
+	config := testSearchConfig
+	config.MaxPlayouts = 50
+	analysts := [2]Analyst{Analysts["uct"](config), Analysts["policy"](config)}
+
+	games := 0
+	err := SelfPlay(analysts, 4, false, func(samples []TrainingSample) error {
+		games += 1
+		for _, sample := range samples {
+			expected := samples[0].Value
+			if sample.State.Player != samples[0].State.Player {
+				expected = -expected
+			}
+
+			if sample.Value != expected {
+				t.Fatal("Expected the players' values to be opposite, got", sample.Value, "and", samples[0].Value)
+			}
+		}
+		return nil
+	})
+
+	if err != nil || games != 4 {
+		t.Error("Expected 4 games to be recorded, got", games, err)
+	}
+}
+
+func TestRunSelfPlay(t *testing.T) {
+	for _, format := range []string{"binary", "jsonl"} {
+		path := filepath.Join(t.TempDir(), "samples")
+		summary, err := RunSelfPlay("policy,random", testSearchConfig, 2, path, format, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		samples, err := LoadTrainingSamples(path)
+		if err != nil {
+			t.Fatal("Failed to read the", format, "samples back:", err)
+		}
+
+		if expected := fmt.Sprintf("wrote %d samples from 2 games", len(samples)); !strings.HasPrefix(summary, expected) || len(samples) < 2*NUM_SYMMETRIES {
+			t.Error("Expected the augmented samples to be written,", summary, "but read", len(samples))
+		}
+	}
+
+	if _, err := RunSelfPlay("policy,random", testSearchConfig, 1, filepath.Join(t.TempDir(), "samples"), "csv", false); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
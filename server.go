@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Serve starts the HTTP API, and the browser UI at "/", on addr. It only
+// returns if the server fails.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/analyze", handleAnalyze)
+	mux.HandleFunc("/render", handleRender)
+	mux.HandleFunc("/", handleWebUI)
+	mux.HandleFunc("/bots", handleBots)
+	mux.HandleFunc("/game", handleGame)
+	mux.HandleFunc("/bot-move", handleBotMove)
+
+	return http.ListenAndServe(addr, mux)
+}
+
+// handleAnalyze searches the board state POSTed in HackerRank's format and
+// responds with the Analysis as JSON. The optional "time" query parameter
+// sets how many seconds to think (TIME_TO_THINK by default).
+func handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST the board state to analyze", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, err := ReadGameState(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, err := requestConfig(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if state.IsOver() {
+		http.Error(w, "the game is already over", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TreeSearchAnalysis(state, config))
+}
+
+// requestConfig returns the SearchConfig to search with for r, with the
+// ThinkTime given in seconds by its optional "time" query parameter.
+func requestConfig(r *http.Request) (SearchConfig, error) {
+	config := DefaultSearchConfig
+	if thinkTime := r.URL.Query().Get("time"); thinkTime != "" {
+		seconds, err := strconv.ParseFloat(thinkTime, 64)
+		if err != nil || seconds <= 0 || seconds > TIME_TO_THINK {
+			return config, errors.New("time must be between 0 and TIME_TO_THINK seconds")
+		}
+		config.ThinkTime = time.Duration(seconds * float64(time.Second))
+	}
+
+	return config, nil
+}
+
+// handleRender draws the board state POSTed in HackerRank's format as an SVG
+// image. With the "analyze" query parameter set, the position is searched
+// first (for "time" seconds, as in /analyze) and the candidate moves are
+// drawn as a heatmap, with arrows for the best "arrows" of them (3 by default).
+func handleRender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST the board state to render", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, err := ReadGameState(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options := RenderOptions{LastMove: &state.LastMove}
+	if r.URL.Query().Has("analyze") && !state.IsOver() {
+		config, err := requestConfig(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		options.Candidates = TreeSearchAnalysis(state, config).Candidates
+		options.Arrows = 3
+		if arrows := r.URL.Query().Get("arrows"); arrows != "" {
+			if options.Arrows, err = strconv.Atoi(arrows); err != nil || options.Arrows < 0 {
+				http.Error(w, "arrows must be a number of moves", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	RenderSVG(w, &state.Board, options)
+}
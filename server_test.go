@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleAnalyze(t *testing.T) {
+	body := "X\n-1 -1\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n"
+	request := httptest.NewRequest("POST", "/analyze?time=0.1", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handleAnalyze(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal("Expected status 200, got", recorder.Code, recorder.Body.String())
+	}
+
+	var analysis Analysis
+	if err := json.NewDecoder(recorder.Body).Decode(&analysis); err != nil {
+		t.Fatal("Failed to decode the analysis:", err)
+	}
+
+	if analysis.Playouts == 0 || len(analysis.Candidates) != 81 {
+		t.Error("Expected an analysis of all 81 moves, got", len(analysis.Candidates))
+	}
+
+	request = httptest.NewRequest("POST", "/analyze", strings.NewReader("X\n"))
+	recorder = httptest.NewRecorder()
+	handleAnalyze(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Error("Expected an invalid board state to be rejected, got", recorder.Code)
+	}
+}
+
+func TestHandleRender(t *testing.T) {
+	body := "X\n-1 -1\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n---------\n"
+	request := httptest.NewRequest("POST", "/render?analyze&time=0.1&arrows=2", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handleRender(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatal("Expected an SVG image, got", recorder.Code, recorder.Body.String())
+	}
+
+	classes := svgClasses(t, recorder.Body)
+	if classes["heat"] != 81 || classes["arrow"] != 2 || classes["forced"] != 9 {
+		t.Error("Unexpected elements in the image:", classes)
+	}
+
+	request = httptest.NewRequest("POST", "/render?analyze&time=100", strings.NewReader(body))
+	recorder = httptest.NewRecorder()
+	handleRender(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Error("Expected too long a time to be rejected, got", recorder.Code)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Game results, from the point of view of the player to move.
+const (
+	SOLVED_LOSS = -1
+	SOLVED_DRAW = 0
+	SOLVED_WIN  = 1
+)
+
+// MAX_SOLVER_NODES is how many positions the Solver may visit
+// before it gives up, which keeps it from blowing the time limit.
+const MAX_SOLVER_NODES = 500000
+
+// Transposition table entry bounds.
+const (
+	boundExact = iota
+	boundLower
+	boundUpper
+)
+
+// ttEntry is what the Solver remembers about a position it has searched.
+type ttEntry struct {
+	value int
+	bound int
+	move  Move
+}
+
+// Solver finds the exact result of a position with an alpha-beta search,
+// remembering the positions it has already seen in a transposition table.
+type Solver struct {
+	MaxNodes int       // Give up after visiting this many positions
+	Deadline time.Time // Give up at this time (the zero time means no deadline)
+
+	nodes   int
+	aborted bool
+	table   map[GameState]ttEntry
+}
+
+// NewSolver returns a Solver which visits at most MAX_SOLVER_NODES positions.
+func NewSolver() *Solver {
+	return &Solver{MaxNodes: MAX_SOLVER_NODES, table: make(map[GameState]ttEntry)}
+}
+
+// Solve returns the result of state with perfect play from both players
+// (SOLVED_WIN, SOLVED_DRAW or SOLVED_LOSS for the player to move) and a move
+// achieving it. The last value is false if the Solver ran out of nodes or
+// time.
+func (solver *Solver) Solve(state *GameState) (int, *Move, bool) {
+	solver.nodes = 0
+	solver.aborted = false
+
+	result := solver.negamax(NewGameHistory(state), SOLVED_LOSS, SOLVED_WIN)
+	if solver.aborted {
+		return 0, nil, false
+	}
+
+	entry, ok := solver.lookup(state)
+	if !ok {
+		// The game is already over, there is no move to make.
+		return result, nil, true
+	}
+
+	return result, entry.move.Copy(), true
+}
+
+// lookup returns what the transposition table remembers about state, with
+// the move turned back from the canonical form of the position to state's.
+// Positions only differ by which board the player to move is sent to, not
+// by the whole last move, and all 8 symmetries of a position share an entry.
+func (solver *Solver) lookup(state *GameState) (ttEntry, bool) {
+	key, symmetry := Canonical(state)
+	entry, ok := solver.table[key]
+	entry.move = symmetry.Inverse().Move(entry.move)
+	return entry, ok
+}
+
+// store remembers entry for state in the transposition table.
+func (solver *Solver) store(state *GameState, entry ttEntry) {
+	key, symmetry := Canonical(state)
+	entry.move = symmetry.Move(entry.move)
+	solver.table[key] = entry
+}
+
+// negamax returns the result of the position in history for the player to
+// move, searching only for results between alpha and beta. The moves it
+// searches are made on history and taken back again.
+func (solver *Solver) negamax(history *GameHistory, alpha, beta int) int {
+	state := &history.GameState
+	solver.nodes += 1
+	if solver.nodes > solver.MaxNodes || (solver.nodes%1024 == 0 && !solver.Deadline.IsZero() && time.Now().After(solver.Deadline)) {
+		solver.aborted = true
+		return SOLVED_DRAW
+	}
+
+	if winner := history.Winner(); winner != EMPTY {
+		// Under the standard rules, only the player who just moved can
+		// have completed a line.
+		if winner == PlayerMark(state.Player) {
+			return SOLVED_WIN
+		}
+		return SOLVED_LOSS
+	}
+
+	moves := history.ValidMoves()
+	if len(moves) == 0 {
+		return SOLVED_DRAW
+	}
+
+	entry, seen := solver.lookup(state)
+	if seen {
+		switch {
+		case entry.bound == boundExact:
+			return entry.value
+		case entry.bound == boundLower && entry.value > alpha:
+			alpha = entry.value
+		case entry.bound == boundUpper && entry.value < beta:
+			beta = entry.value
+		}
+
+		if alpha >= beta {
+			return entry.value
+		}
+
+		// Try the move that was best last time first.
+		for i, move := range moves {
+			if *move == entry.move {
+				moves[0], moves[i] = moves[i], moves[0]
+				break
+			}
+		}
+	}
+
+	originalAlpha := alpha
+	best := SOLVED_LOSS - 1
+	var bestMove Move
+
+	for _, move := range moves {
+		history.Make(move)
+		value := -solver.negamax(history, -beta, -alpha)
+		history.Unmake()
+		if solver.aborted {
+			return SOLVED_DRAW
+		}
+
+		if value > best {
+			best = value
+			bestMove = *move
+		}
+
+		if best > alpha {
+			alpha = best
+		}
+
+		if alpha >= beta {
+			break
+		}
+	}
+
+	entry = ttEntry{value: best, bound: boundExact, move: bestMove}
+	if best <= originalAlpha {
+		entry.bound = boundUpper
+	} else if best >= beta {
+		entry.bound = boundLower
+	}
+	solver.store(state, entry)
+
+	return best
+}
+
+// resultName returns a readable name for a SOLVED_* result.
+func resultName(result int) string {
+	switch result {
+	case SOLVED_WIN:
+		return "win"
+	case SOLVED_LOSS:
+		return "loss"
+	}
+
+	return "draw"
+}
+
+// solveEndgame solves state exactly if at most threshold empty squares are
+// left to play on. If it proves a win or a draw, it returns an Analysis with
+// the move that achieves it, otherwise nil, and the bot should search as usual.
+func solveEndgame(state *GameState, threshold int, deadline time.Time) *Analysis {
+	emptySquares := len(state.Board.ValidMoves())
+	if emptySquares == 0 || emptySquares > threshold {
+		return nil
+	}
+
+	start := time.Now()
+	solver := NewSolver()
+	solver.Deadline = deadline
+	result, move, solved := solver.Solve(state)
+	slog.Debug("Endgame solver finished", "emptySquares", emptySquares, "nodes", solver.nodes, "solved", solved, "result", resultName(result))
+	if !solved || result == SOLVED_LOSS || move == nil {
+		// A lost position is better left to the search, which
+		// at least picks the move that is hardest to punish.
+		return nil
+	}
+
+	candidate := CandidateMove{Move: *move, Score: float64(result)}
+	if result == SOLVED_WIN {
+		candidate.WinRate = 1
+	} else {
+		candidate.DrawRate = 1
+	}
+
+	analysis := &Analysis{Candidates: []CandidateMove{candidate}, TimeUsed: time.Since(start), Proven: resultName(result)}
+
+	// Follow the best moves the solver remembers for the principal variation.
+	line := *state
+	for entry, ok := solver.lookup(&line); ok && !line.IsOver(); entry, ok = solver.lookup(&line) {
+		analysis.PrincipalVariation = append(analysis.PrincipalVariation, entry.move)
+		line.Play(&entry.move)
+	}
+
+	return analysis
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// randomEndgame plays random moves from a new game until at most
+// emptySquares squares are left to play on, or the game is over.
+func randomEndgame(random *rand.Rand, emptySquares int) *GameState {
+	state := NewGame()
+	for !state.IsOver() && len(state.Board.ValidMoves()) > emptySquares {
+		moves := state.ValidMoves()
+		state.Play(moves[random.Intn(len(moves))])
+	}
+
+	return state
+}
+
+// minimax returns the result of state for the player to move, the slow
+// way: without pruning or remembering anything.
+func minimax(state *GameState) int {
+	if state.Board.HasWinner() != EMPTY {
+		return SOLVED_LOSS
+	}
+
+	best := SOLVED_LOSS - 1
+	for _, move := range state.ValidMoves() {
+		next := *state
+		next.Play(move)
+		if value := -minimax(&next); value > best {
+			best = value
+		}
+	}
+
+	if best < SOLVED_LOSS {
+		return SOLVED_DRAW // No moves left to play
+	}
+
+	return best
+}
+
+func TestSolverAgreesWithMinimax(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	results := make(map[int]int)
+
+	for i := 0; i < 30; i++ {
+		state := randomEndgame(random, 10)
+		result, move, solved := NewSolver().Solve(state)
+		if !solved {
+			t.Fatal("The solver ran out of nodes on a position with 10 empty squares!")
+		}
+
+		if expected := minimax(state); result != expected {
+			t.Fatalf("Solve() = %s, expected %s", resultName(result), resultName(expected))
+		}
+		results[result] += 1
+
+		if state.IsOver() {
+			continue
+		}
+
+		// Playing the best move should leave the opponent with the opposite result.
+		state.Play(move)
+		if minimax(state) != -result {
+			t.Error("The solver's move", *move, "does not achieve a", resultName(result))
+		}
+	}
+
+	if len(results) < 2 {
+		t.Error("Expected the random endgames to have different results, got", results)
+	}
+}
+
+func TestSolverGivesUp(t *testing.T) {
+	solver := NewSolver()
+	solver.MaxNodes = 1000
+
+	if _, _, solved := solver.Solve(NewGame()); solved {
+		t.Error("The solver should not be able to solve a new game in 1000 nodes!")
+	}
+
+	solver = NewSolver()
+	solver.MaxNodes = math.MaxInt
+	solver.Deadline = time.Now().Add(50 * time.Millisecond)
+	start := time.Now()
+	if _, _, solved := solver.Solve(NewGame()); solved || time.Since(start) > time.Second {
+		t.Error("The solver should give up on a new game once out of time, it took", time.Since(start))
+	}
+}
+
+func TestSolveEndgame(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+
+	if solveEndgame(NewGame(), DefaultSearchConfig.SolverThreshold, time.Time{}) != nil {
+		t.Error("The endgame solver should not try to solve a new game!")
+	}
+
+	for i := 0; i < 10; i++ {
+		state := randomEndgame(random, 12)
+		analysis := solveEndgame(state, 12, time.Time{})
+		if analysis == nil {
+			continue
+		}
+
+		if analysis.Proven != resultName(minimax(state)) || len(analysis.PrincipalVariation) == 0 {
+			t.Error("Expected a proven", resultName(minimax(state)), "with a principal variation, got", analysis.Proven)
+		}
+
+		if analysis.PrincipalVariation[0] != *analysis.BestMove() {
+			t.Error("The principal variation should start with the best move!")
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Sizes of the rendered board, in pixels.
+const (
+	SQUARE_SIZE  = 40
+	BOARD_MARGIN = 10
+	BOARD_SIZE   = 9*SQUARE_SIZE + 2*BOARD_MARGIN
+)
+
+// Colors of the rendered board.
+const (
+	PLAYER_1_COLOR  = "#c0392b"
+	PLAYER_2_COLOR  = "#2471a3"
+	HIGHLIGHT_COLOR = "#fcf3b0"
+	HEATMAP_COLOR   = "#27ae60"
+	ARROW_COLOR     = "#8e44ad"
+	PLAYED_COLOR    = "#f5b041"
+	GRID_COLOR      = "#333333"
+)
+
+// RenderOptions says what to draw on top of a position besides the pieces.
+type RenderOptions struct {
+	// LastMove is the move which led to the position, which decides
+	// the board to highlight as the one the player to move is sent to
+	// (nil highlights nothing).
+	LastMove *Move
+
+	// Played is the move to mark as the one just made (nil marks nothing).
+	Played *Move
+
+	// Candidates are colored in a heatmap by their share of the search's
+	// visits, or by their expected result for searches without visits.
+	Candidates []CandidateMove
+
+	// Arrows is how many of the best Candidates get an arrow, from the
+	// square of the move to the board it sends the opponent to.
+	Arrows int
+
+	// Cursor is the square RenderText draws in brackets, for picking
+	// a move in the terminal (nil draws no cursor).
+	Cursor *Move
+}
+
+// squareOrigin returns the top left corner of the square move is made on.
+func squareOrigin(move Move) (int, int) {
+	row, column := move.BoardX*3+move.TileX, move.BoardY*3+move.TileY
+	return BOARD_MARGIN + column*SQUARE_SIZE, BOARD_MARGIN + row*SQUARE_SIZE
+}
+
+// boardOrigin returns the top left corner of the board at x, y.
+func boardOrigin(x, y int) (int, int) {
+	return squareOrigin(Move{x, y, 0, 0})
+}
+
+// candidateWeights returns how strongly to color each of candidates, from 0
+// to 1 for the candidate with the most visits, or with the best expected
+// result if none of them have visits.
+func candidateWeights(candidates []CandidateMove) []float64 {
+	weights := make([]float64, len(candidates))
+	best := 0.0
+	for i, candidate := range candidates {
+		weights[i] = float64(candidate.Visits)
+		best = max(best, weights[i])
+	}
+
+	if best == 0 {
+		for i, candidate := range candidates {
+			weights[i] = candidate.WinRate + candidate.DrawRate/2
+			best = max(best, weights[i])
+		}
+	}
+
+	for i := range weights {
+		if best > 0 {
+			weights[i] /= best
+		}
+	}
+
+	return weights
+}
+
+// highlightedBoards returns the boards the player to move in state may play
+// on under its rules: the board they are sent to, or every board which is
+// neither won nor full if they are free to choose. A nil state, like a game
+// which is over, has none.
+func highlightedBoards(state *GameState) [][2]int {
+	if state == nil || state.IsOver() {
+		return nil
+	}
+
+	if x, y, forced := state.ForcedBoard(); forced {
+		return [][2]int{{x, y}}
+	}
+
+	var boards [][2]int
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 3; y++ {
+			if state.Board[x][y].HasWinner() == EMPTY && hasEmptyTile(&state.Board[x][y], -1, -1) {
+				boards = append(boards, [2]int{x, y})
+			}
+		}
+	}
+
+	return boards
+}
+
+// position returns the position board shows after options.LastMove, under
+// DefaultRules, or nil without a LastMove.
+func (options *RenderOptions) position(board *UltimateBoard) *GameState {
+	if options.LastMove == nil {
+		return nil
+	}
+
+	// The player to move does not change where they may play.
+	return NewGameState(1, options.LastMove, board)
+}
+
+// RenderSVG draws board as an SVG image to w: the 9x9 grid with thick lines
+// between the boards, the X and O pieces, a large mark over every board
+// already won and whatever options ask for.
+func RenderSVG(w io.Writer, board *UltimateBoard, options RenderOptions) error {
+	buffer := bufio.NewWriter(w)
+	fmt.Fprintf(buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", BOARD_SIZE, BOARD_SIZE, BOARD_SIZE, BOARD_SIZE)
+	fmt.Fprintf(buffer, `<defs><marker id="arrowhead" markerWidth="6" markerHeight="6" refX="3" refY="3" orient="auto"><path d="M0,0 L6,3 L0,6 z" fill="%s"/></marker></defs>`+"\n", ARROW_COLOR)
+	fmt.Fprintf(buffer, `<rect width="%d" height="%d" fill="white"/>`+"\n", BOARD_SIZE, BOARD_SIZE)
+
+	for _, highlighted := range highlightedBoards(options.position(board)) {
+		x, y := boardOrigin(highlighted[0], highlighted[1])
+		fmt.Fprintf(buffer, `<rect class="forced" x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, 3*SQUARE_SIZE, 3*SQUARE_SIZE, HIGHLIGHT_COLOR)
+	}
+
+	if options.Played != nil {
+		x, y := squareOrigin(*options.Played)
+		fmt.Fprintf(buffer, `<rect class="played" x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, SQUARE_SIZE, SQUARE_SIZE, PLAYED_COLOR)
+	}
+
+	for i, weight := range candidateWeights(options.Candidates) {
+		x, y := squareOrigin(options.Candidates[i].Move)
+		fmt.Fprintf(buffer, `<rect class="heat" x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="%.3f"/>`+"\n", x, y, SQUARE_SIZE, SQUARE_SIZE, HEATMAP_COLOR, 0.1+0.7*weight)
+	}
+
+	// The grid, with every third line thicker.
+	for line := 0; line <= 9; line++ {
+		width := 1
+		if line%3 == 0 {
+			width = 4
+		}
+		offset := BOARD_MARGIN + line*SQUARE_SIZE
+		end := BOARD_MARGIN + 9*SQUARE_SIZE
+		fmt.Fprintf(buffer, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n", offset, BOARD_MARGIN, offset, end, GRID_COLOR, width)
+		fmt.Fprintf(buffer, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n", BOARD_MARGIN, offset, end, offset, GRID_COLOR, width)
+	}
+
+	for bx := 0; bx < 3; bx++ {
+		for by := 0; by < 3; by++ {
+			for tx := 0; tx < 3; tx++ {
+				for ty := 0; ty < 3; ty++ {
+					x, y := squareOrigin(Move{bx, by, tx, ty})
+					writeSVGMark(buffer, board[bx][by][tx][ty], x, y, SQUARE_SIZE, "piece")
+				}
+			}
+
+			// Cover the pieces of a won board, inside its thick lines, with the winner's mark.
+			if winner := board[bx][by].HasWinner(); winner != EMPTY {
+				x, y := boardOrigin(bx, by)
+				fmt.Fprintf(buffer, `<rect x="%d" y="%d" width="%d" height="%d" fill="white" fill-opacity="0.7"/>`+"\n", x+2, y+2, 3*SQUARE_SIZE-4, 3*SQUARE_SIZE-4)
+				writeSVGMark(buffer, winner, x, y, 3*SQUARE_SIZE, "won")
+			}
+		}
+	}
+
+	for i := 0; i < options.Arrows && i < len(options.Candidates); i++ {
+		move := options.Candidates[i].Move
+		x1, y1 := squareOrigin(move)
+		x2, y2 := boardOrigin(move.TileX, move.TileY)
+		fmt.Fprintf(buffer, `<line class="arrow" x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="4" stroke-opacity="0.8" marker-end="url(#arrowhead)"/>`+"\n",
+			x1+SQUARE_SIZE/2, y1+SQUARE_SIZE/2, x2+3*SQUARE_SIZE/2, y2+3*SQUARE_SIZE/2, ARROW_COLOR)
+	}
+
+	fmt.Fprintln(buffer, "</svg>")
+	return buffer.Flush()
+}
+
+// writeSVGMark draws the mark of square (an X, an O or nothing if it is
+// EMPTY) in the size by size square at x, y, with the given class.
+func writeSVGMark(w io.Writer, square, x, y, size int, class string) {
+	padding := size / 5
+	switch square {
+	case PLAYER_1_CONTROLLED:
+		fmt.Fprintf(w, `<path class="%s x" d="M%d,%d L%d,%d M%d,%d L%d,%d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`+"\n",
+			class, x+padding, y+padding, x+size-padding, y+size-padding, x+size-padding, y+padding, x+padding, y+size-padding, PLAYER_1_COLOR, size/10)
+	case PLAYER_2_CONTROLLED:
+		fmt.Fprintf(w, `<circle class="%s o" cx="%d" cy="%d" r="%d" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+			class, x+size/2, y+size/2, size/2-padding, PLAYER_2_COLOR, size/10)
+	}
+}
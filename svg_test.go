@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// svgClasses parses the SVG image in r and counts the elements of each class.
+func svgClasses(t *testing.T, r io.Reader) map[string]int {
+	classes := make(map[string]int)
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return classes
+		}
+		if err != nil {
+			t.Fatal("Invalid SVG:", err)
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			for _, attr := range element.Attr {
+				if attr.Name.Local == "class" {
+					for _, class := range strings.Fields(attr.Value) {
+						classes[class] += 1
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	state := NewGame()
+	for _, move := range []Move{{0, 0, 1, 1}, {1, 1, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 1}, {0, 1, 0, 0}, {0, 0, 0, 2}, {0, 2, 2, 2}, {2, 2, 0, 0}, {0, 0, 2, 2}} {
+		state.Play(&move)
+	}
+
+	var buffer bytes.Buffer
+	if err := RenderSVG(&buffer, &state.Board, RenderOptions{LastMove: &state.LastMove}); err != nil {
+		t.Fatal(err)
+	}
+
+	// X won board (0,0) with a diagonal and O is sent to board (2,2).
+	classes := svgClasses(t, &buffer)
+	if classes["piece"] != 9 || classes["x"] != 6 || classes["o"] != 4 || classes["won"] != 1 || classes["forced"] != 1 {
+		t.Error("Unexpected elements in the image:", classes)
+	}
+
+	analysis := &Analysis{Candidates: []CandidateMove{{Move: Move{2, 2, 0, 0}, Visits: 10}, {Move: Move{2, 2, 1, 1}, Visits: 5}, {Move: Move{2, 2, 0, 1}}}}
+	buffer.Reset()
+	RenderSVG(&buffer, &state.Board, RenderOptions{Candidates: analysis.Candidates, Arrows: 2})
+	classes = svgClasses(t, &buffer)
+	if classes["heat"] != 3 || classes["arrow"] != 2 || classes["forced"] != 0 {
+		t.Error("Unexpected elements in the image of the analysis:", classes)
+	}
+}
+
+func TestHighlightedBoards(t *testing.T) {
+	// X has won the top row of boards and sent O to board (0,0), which is won.
+	state := NewGame()
+	for x := 0; x < 3; x++ {
+		for tile := 0; tile < 3; tile++ {
+			state.Board[x][0][tile][tile] = PLAYER_1_CONTROLLED
+		}
+	}
+	state.Board[1][1][2][0] = PLAYER_2_CONTROLLED
+	state.Player = 2
+	state.LastMove = Move{2, 0, 0, 0}
+
+	if boards := highlightedBoards(state); boards != nil {
+		t.Error("Expected no boards once the game is won, got", boards)
+	}
+
+	// Under the most-boards rules, play goes on on the six open boards.
+	state.Rules = RULES_MOST_BOARDS
+	if boards := highlightedBoards(state); len(boards) != 6 {
+		t.Error("Expected the six open boards under the most-boards rules, got", boards)
+	}
+
+	// Without the forced board rule, O may play on any open board.
+	state = NewGame()
+	state.Rules = RULES_FREE_MOVES
+	state.Play(&Move{1, 1, 0, 2})
+	if boards := highlightedBoards(state); len(boards) != 9 {
+		t.Error("Expected every board under the free rules, got", boards)
+	}
+	state.Rules = DefaultRules
+	if boards := highlightedBoards(state); len(boards) != 1 || boards[0] != [2]int{0, 2} {
+		t.Error("Expected board 0 2 under the standard rules, got", boards)
+	}
+}
+
+func TestCandidateWeights(t *testing.T) {
+	weights := candidateWeights([]CandidateMove{{Visits: 10}, {Visits: 5}, {Visits: 0}})
+	if weights[0] != 1 || weights[1] != 0.5 || weights[2] != 0 {
+		t.Error("Expected weights by visits, got", weights)
+	}
+
+	weights = candidateWeights([]CandidateMove{{WinRate: 0.5}, {WinRate: 0.2, DrawRate: 0.2}})
+	if weights[0] != 1 || math.Abs(weights[1]-0.6) > 1e-9 {
+		t.Error("Expected weights by expected result, got", weights)
+	}
+}
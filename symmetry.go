@@ -0,0 +1,147 @@
+package main
+
+// A Symmetry is one of the 8 ways to rotate or reflect the board which
+// keep the rules intact. It is applied the same way to the big board and
+// to every small board, so the board a move sends the opponent to moves
+// along with it.
+type Symmetry int
+
+// NUM_SYMMETRIES is the number of Symmetries, the identity (0) included.
+const NUM_SYMMETRIES = 8
+
+// The bits of a Symmetry, applied in this order.
+const (
+	symmetryTranspose = 1 << iota // Swap x and y
+	symmetryFlipX                 // Mirror x
+	symmetryFlipY                 // Mirror y
+)
+
+// point applies the symmetry to the coordinates (x, y) on a 3x3 board.
+// The coordinates -1, -1, meaning no board, are left alone.
+func (symmetry Symmetry) point(x, y int) (int, int) {
+	if x == -1 && y == -1 {
+		return x, y
+	}
+
+	if symmetry&symmetryTranspose != 0 {
+		x, y = y, x
+	}
+	if symmetry&symmetryFlipX != 0 {
+		x = 2 - x
+	}
+	if symmetry&symmetryFlipY != 0 {
+		y = 2 - y
+	}
+
+	return x, y
+}
+
+// Move returns move transformed by the symmetry.
+func (symmetry Symmetry) Move(move Move) Move {
+	move.BoardX, move.BoardY = symmetry.point(move.BoardX, move.BoardY)
+	move.TileX, move.TileY = symmetry.point(move.TileX, move.TileY)
+	return move
+}
+
+// Board returns board transformed by the symmetry.
+func (symmetry Symmetry) Board(board *UltimateBoard) UltimateBoard {
+	var transformed UltimateBoard
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				for l := 0; l < 3; l++ {
+					m := symmetry.Move(Move{i, j, k, l})
+					transformed[m.BoardX][m.BoardY][m.TileX][m.TileY] = board[i][j][k][l]
+				}
+			}
+		}
+	}
+
+	return transformed
+}
+
+// State returns state transformed by the symmetry.
+func (symmetry Symmetry) State(state *GameState) GameState {
+	return GameState{Board: symmetry.Board(&state.Board), LastMove: symmetry.Move(state.LastMove), Player: state.Player, Rules: state.Rules}
+}
+
+// symmetrySources maps every square, by moveIndex, to the square it is
+// moved from by each Symmetry.
+var symmetrySources = newSymmetrySources()
+
+// symmetryInverses holds the Symmetry undoing each Symmetry.
+var symmetryInverses = newSymmetryInverses()
+
+// newSymmetrySources returns the table for symmetrySources.
+func newSymmetrySources() [NUM_SYMMETRIES][NETWORK_MOVES]int {
+	var sources [NUM_SYMMETRIES][NETWORK_MOVES]int
+	for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+		for i := 0; i < NETWORK_MOVES; i++ {
+			move := symmetry.Move(Move{i / 27, i / 9 % 3, i / 3 % 3, i % 3})
+			sources[symmetry][moveIndex(&move)] = i
+		}
+	}
+
+	return sources
+}
+
+// newSymmetryInverses returns the table for symmetryInverses.
+func newSymmetryInverses() [NUM_SYMMETRIES]Symmetry {
+	var inverses [NUM_SYMMETRIES]Symmetry
+	for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+		for inverse := Symmetry(0); inverse < NUM_SYMMETRIES; inverse++ {
+			if x, y := inverse.point(symmetry.point(0, 1)); x == 0 && y == 1 {
+				if x, y := inverse.point(symmetry.point(1, 2)); x == 1 && y == 2 {
+					inverses[symmetry] = inverse
+				}
+			}
+		}
+	}
+
+	return inverses
+}
+
+// Inverse returns the Symmetry undoing symmetry.
+func (symmetry Symmetry) Inverse() Symmetry {
+	return symmetryInverses[symmetry]
+}
+
+// Canonical returns the canonical form of state, the one of its 8
+// transformations whose squares compare smallest, and the Symmetry turning
+// state into it. All transformations of a position share a canonical form.
+// Like the solver's transposition table keys, the canonical form only keeps
+// the board the player to move is sent to from the last move, as the tile
+// of a move on board (0, 0) (-1, -1 if the player may play anywhere).
+func Canonical(state *GameState) (GameState, Symmetry) {
+	var squares [NETWORK_MOVES]int
+	for i := range squares {
+		squares[i] = state.Board[i/27][i/9%3][i/3%3][i%3]
+	}
+	x, y, _ := state.ForcedBoard()
+
+	best := Symmetry(0)
+	for symmetry := Symmetry(1); symmetry < NUM_SYMMETRIES; symmetry++ {
+		sources, bestSources := &symmetrySources[symmetry], &symmetrySources[best]
+		for i := 0; i < NETWORK_MOVES; i++ {
+			if square, bestSquare := squares[sources[i]], squares[bestSources[i]]; square != bestSquare {
+				if square < bestSquare {
+					best = symmetry
+				}
+				break
+			}
+
+			if i == NETWORK_MOVES-1 {
+				// The same squares, so the forced board decides.
+				bx, by := best.point(x, y)
+				sx, sy := symmetry.point(x, y)
+				if sx < bx || (sx == bx && sy < by) {
+					best = symmetry
+				}
+			}
+		}
+	}
+
+	canonical := GameState{Board: best.Board(&state.Board), Player: state.Player, Rules: state.Rules}
+	canonical.LastMove.TileX, canonical.LastMove.TileY = best.point(x, y)
+	return canonical, best
+}
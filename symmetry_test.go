@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomPosition plays between 0 and 60 random moves from a new game.
+func randomPosition(random *rand.Rand) *GameState {
+	state := NewGame()
+	for moves := random.Intn(61); moves > 0 && !state.IsOver(); moves-- {
+		validMoves := state.ValidMoves()
+		state.Play(validMoves[random.Intn(len(validMoves))])
+	}
+
+	return state
+}
+
+func TestSymmetriesAreDistinct(t *testing.T) {
+	seen := make(map[Move]Symmetry)
+	for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+		move := symmetry.Move(Move{0, 1, 1, 2})
+		if other, ok := seen[move]; ok {
+			t.Error("Symmetries", other, "and", symmetry, "both map (0,1,1,2) to", move)
+		}
+		seen[move] = symmetry
+
+		for i := 0; i < NETWORK_MOVES; i++ {
+			original := Move{i / 27, i / 9 % 3, i / 3 % 3, i % 3}
+			if back := symmetry.Inverse().Move(symmetry.Move(original)); back != original {
+				t.Fatal("The inverse of symmetry", symmetry, "turns", original, "into", back)
+			}
+		}
+	}
+}
+
+func TestSymmetryInvariants(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		state := randomPosition(random)
+		for symmetry := Symmetry(0); symmetry < NUM_SYMMETRIES; symmetry++ {
+			transformed := symmetry.State(state)
+			if transformed.Board.HasWinner() != state.Board.HasWinner() || transformed.IsOver() != state.IsOver() {
+				t.Fatal("Symmetry", symmetry, "changed the winner of\n", state.Board, "\nto\n", transformed.Board)
+			}
+
+			for x := 0; x < 3; x++ {
+				for y := 0; y < 3; y++ {
+					tx, ty := symmetry.point(x, y)
+					if transformed.Board[tx][ty].HasWinner() != state.Board[x][y].HasWinner() {
+						t.Fatal("Symmetry", symmetry, "changed the winner of board", x, y)
+					}
+				}
+			}
+
+			// The legal moves are the transformations of the legal moves.
+			legal := make(map[Move]bool)
+			for _, move := range transformed.ValidMoves() {
+				legal[*move] = true
+			}
+
+			validMoves := state.ValidMoves()
+			if len(validMoves) != len(legal) {
+				t.Fatal("Symmetry", symmetry, "changed the number of legal moves from", len(validMoves), "to", len(legal))
+			}
+			for _, move := range validMoves {
+				if !legal[symmetry.Move(*move)] {
+					t.Fatal("Symmetry", symmetry, "made the move", *move, "illegal")
+				}
+			}
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		state := randomPosition(random)
+		canonical, symmetry := Canonical(state)
+
+		if expected := symmetry.State(state); canonical.Board != expected.Board || canonical.Player != state.Player {
+			t.Fatal("Canonical did not return the position transformed by", symmetry)
+		}
+
+		x, y, _ := state.ForcedBoard()
+		cx, cy, _ := canonical.ForcedBoard()
+		if tx, ty := symmetry.point(x, y); cx != tx || cy != ty {
+			t.Fatal("Canonical sends the player to board", cx, cy, "instead of", tx, ty)
+		}
+
+		for other := Symmetry(0); other < NUM_SYMMETRIES; other++ {
+			transformed := other.State(state)
+			if otherCanonical, _ := Canonical(&transformed); otherCanonical != canonical {
+				t.Fatal("Symmetry", other, "of a position has a different canonical form")
+			}
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// TUI_ANALYSIS_INTERVAL is how often the terminal UI's analysis pane is
+// updated while the background search goes on.
+const TUI_ANALYSIS_INTERVAL = 250 * time.Millisecond
+
+// TUI_HELP lists the keys of the terminal UI.
+const TUI_HELP = "arrows/hjkl: move cursor  enter/space: play  g: bot plays  r: bot replies on/off\n" +
+	"a: analysis on/off  ,/.: back/forward  </>: start/end  s: save  o: open  q: quit"
+
+// tui holds the state of the terminal UI: the game, the position shown and
+// what is drawn on top of it. It only changes through handleKey and the
+// results of the background work, so it can be driven without a terminal.
+type tui struct {
+	game     *GameHistory // The position shown, with the moves after it to redo
+	row      int          // The cursor, as in RenderText
+	column   int
+	gamePath string
+
+	analysis     *Analysis // Of the position shown, nil until the search reports
+	showAnalysis bool
+	autoReply    bool // The bot replies to every move made with the cursor
+	botRequested bool // The bot should make the next move
+	thinking     bool // The bot is picking a move
+	message      string
+}
+
+// newTUI returns a tui for a new game, saving and opening games at gamePath.
+func newTUI(gamePath string) *tui {
+	return &tui{game: NewGameHistory(NewGame()), row: 4, column: 4, gamePath: gamePath, showAnalysis: true}
+}
+
+// state returns a copy of the position shown.
+func (ui *tui) state() *GameState {
+	state := ui.game.GameState
+	return &state
+}
+
+// moves returns the moves of the game, including those after the position shown.
+func (ui *tui) moves() []Move {
+	return append(ui.game.MovesMade(), ui.game.MovesUndone()...)
+}
+
+// shown returns how many of the moves are made in the position shown.
+func (ui *tui) shown() int {
+	return len(ui.game.made)
+}
+
+// cursor returns the move of the square under the cursor.
+func (ui *tui) cursor() Move {
+	return Move{ui.row / 3, ui.column / 3, ui.row % 3, ui.column % 3}
+}
+
+// play makes move in the position shown, replacing the moves which came
+// after it. It returns false, and changes nothing, if move is not legal.
+func (ui *tui) play(move Move) bool {
+	state := ui.state()
+	if state.IsOver() || !isValidMove(state, &move) {
+		return false
+	}
+
+	ui.game.Make(&move)
+	ui.analysis = nil
+	return true
+}
+
+// goTo shows the position after the first shown moves of the game, taking
+// moves back or making them again.
+func (ui *tui) goTo(shown int) {
+	if shown != ui.shown() {
+		ui.analysis = nil
+	}
+	for ui.shown() > shown && ui.game.Unmake() != nil {
+	}
+	for ui.shown() < shown && ui.game.Redo() != nil {
+	}
+}
+
+// handleKey updates the UI for key, as read by readKeys, and returns true
+// if the UI should quit.
+func (ui *tui) handleKey(key string) bool {
+	ui.message = ""
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "up", "k":
+		ui.row = max(0, ui.row-1)
+	case "down", "j":
+		ui.row = min(8, ui.row+1)
+	case "left", "h":
+		ui.column = max(0, ui.column-1)
+	case "right", "l":
+		ui.column = min(8, ui.column+1)
+	case "enter", " ":
+		if !ui.play(ui.cursor()) {
+			ui.message = "That move is not legal."
+		} else if ui.autoReply && !ui.state().IsOver() {
+			ui.botRequested = true
+		}
+	case "g":
+		if ui.state().IsOver() {
+			ui.message = "The game is over."
+		} else {
+			ui.botRequested = true
+		}
+	case "r":
+		ui.autoReply = !ui.autoReply
+		ui.message = fmt.Sprintf("The bot replies to your moves: %t", ui.autoReply)
+	case "a":
+		ui.showAnalysis = !ui.showAnalysis
+	case ",":
+		ui.goTo(ui.shown() - 1)
+	case ".":
+		ui.goTo(ui.shown() + 1)
+	case "<":
+		ui.goTo(0)
+	case ">":
+		ui.goTo(len(ui.moves()))
+	case "s":
+		ui.message = ui.save()
+	case "o":
+		ui.message = ui.open()
+	}
+
+	return false
+}
+
+// save writes the game to the game record file and returns what happened.
+func (ui *tui) save() string {
+	file, err := os.Create(ui.gamePath)
+	if err != nil {
+		return err.Error()
+	}
+
+	err = GameRecord{Moves: ui.moves()}.Save(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("Saved %d moves to %s.", len(ui.moves()), ui.gamePath)
+}
+
+// open reads the game from the game record file, showing its final
+// position, and returns what happened.
+func (ui *tui) open() string {
+	record, err := LoadGameRecord(ui.gamePath)
+	if err != nil {
+		return err.Error()
+	}
+
+	ui.game = NewGameHistory(NewGame())
+	for i := range record.Moves {
+		ui.game.Make(&record.Moves[i])
+	}
+	ui.analysis = nil
+	return fmt.Sprintf("Opened %d moves from %s.", len(record.Moves), ui.gamePath)
+}
+
+// status describes the position shown: who is to move and where, or the result.
+func (ui *tui) status() string {
+	state := ui.state()
+	switch winner := state.Winner(); {
+	case winner != EMPTY:
+		return fmt.Sprintf("%c won the game.", winner)
+	case state.IsOver():
+		return "The game is a draw."
+	}
+
+	where := "on any board"
+	if x, y, forced := state.ForcedBoard(); forced {
+		where = fmt.Sprintf("on board %d %d", x, y)
+	}
+	return fmt.Sprintf("%c to move %s.", PlayerMark(state.Player), where)
+}
+
+// render draws the whole screen, with lines separated by "\n".
+func (ui *tui) render(colors bool) string {
+	var builder strings.Builder
+	state := ui.state()
+	cursor := ui.cursor()
+	options := RenderOptions{LastMove: &state.LastMove, Cursor: &cursor}
+	if moves := ui.game.MovesMade(); len(moves) > 0 {
+		options.Played = &moves[len(moves)-1]
+	}
+	if ui.showAnalysis && ui.analysis != nil {
+		options.Candidates = ui.analysis.Candidates
+	}
+
+	fmt.Fprintf(&builder, "Ultimate Tic-Tac-Toe, move %d of %d. %s\n", ui.shown(), len(ui.moves()), ui.status())
+	builder.WriteString(RenderText(&state.Board, options, colors))
+
+	if ui.showAnalysis {
+		builder.WriteString(ui.renderAnalysis())
+	}
+
+	builder.WriteString(ui.renderMoves())
+
+	switch {
+	case ui.thinking:
+		builder.WriteString("The bot is thinking...\n")
+	case ui.message != "":
+		builder.WriteString(ui.message + "\n")
+	default:
+		builder.WriteString("\n")
+	}
+	builder.WriteString(TUI_HELP + "\n")
+
+	return builder.String()
+}
+
+// renderAnalysis draws the best candidates of the analysis of the position shown.
+func (ui *tui) renderAnalysis() string {
+	if ui.state().IsOver() {
+		return "\n"
+	}
+	if ui.analysis == nil {
+		return "Analyzing...\n"
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d playouts", ui.analysis.Playouts)
+	if ui.analysis.Proven != "" {
+		fmt.Fprintf(&builder, ", proven %s", ui.analysis.Proven)
+	}
+	builder.WriteString("\n")
+
+	for i, candidate := range ui.analysis.Candidates {
+		if i == 5 {
+			break
+		}
+		m := candidate.Move
+		fmt.Fprintf(&builder, "  %d %d %d %d  %7d visits  %5.1f%% win  %5.1f%% draw\n", m.BoardX, m.BoardY, m.TileX, m.TileY, candidate.Visits, candidate.WinRate*100, candidate.DrawRate*100)
+	}
+
+	return builder.String()
+}
+
+// renderMoves draws the moves of the game leading up to the position
+// shown, with the two players' moves on the same line.
+func (ui *tui) renderMoves() string {
+	var lines []string
+	moves, shown := ui.moves(), ui.shown()
+	for i := 0; i < len(moves); i += 2 {
+		line := fmt.Sprintf("%3d.", i/2+1)
+		for j := i; j < i+2 && j < len(moves); j++ {
+			m := moves[j]
+			marker := " "
+			if j == shown-1 {
+				marker = "*"
+			}
+			line += fmt.Sprintf(" %s%d %d %d %d", marker, m.BoardX, m.BoardY, m.TileX, m.TileY)
+		}
+		lines = append(lines, line)
+	}
+
+	// Only show a few lines, ending just after the position shown.
+	end := min(len(lines), shown/2+3)
+	start := max(0, end-6)
+	return "Moves:\n" + strings.Join(append(lines[start:end], ""), "\n")
+}
+
+// readKeys reads key presses from r and sends their names to keys: the
+// character typed, "up", "down", "left", "right", "enter" or "ctrl-c".
+// It closes keys once r is exhausted.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buffer := make([]byte, 64)
+	for {
+		n, err := r.Read(buffer)
+		for input := buffer[:n]; len(input) > 0; {
+			key := string(input[:1])
+			length := 1
+			switch {
+			case len(input) >= 3 && input[0] == '\x1b' && input[1] == '[':
+				key, length = map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}[input[2]], 3
+			case input[0] == '\r' || input[0] == '\n':
+				key = "enter"
+			case input[0] == 3:
+				key = "ctrl-c"
+			}
+
+			if key != "" {
+				keys <- key
+			}
+			input = input[length:]
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// positionAnalysis is an update of the background search of state.
+type positionAnalysis struct {
+	state    GameState
+	analysis *Analysis
+}
+
+// analyzePosition searches state with config until stop is closed, sending
+// the analysis to updates every TUI_ANALYSIS_INTERVAL.
+func analyzePosition(state GameState, config SearchConfig, stop <-chan struct{}, updates chan<- positionAnalysis) {
+	config.ThinkTime = TUI_ANALYSIS_INTERVAL
+	config.MaxPlayouts = 0
+	tree := NewSearchTree(&state, config)
+	for {
+		tree.Search(stop)
+		select {
+		case <-stop:
+			return
+		case updates <- positionAnalysis{state, tree.Analysis()}:
+		}
+
+		if tree.root.proven {
+			// Searching any further is pointless.
+			<-stop
+			return
+		}
+	}
+}
+
+// stty runs stty on tty with args and returns its output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// RunTUI runs the terminal UI on the terminal tty until the user quits,
+// with a search of the position shown going on in the background and bot
+// making the moves asked of it. Games are saved to and opened from gamePath.
+func RunTUI(tty *os.File, config SearchConfig, bot Analyst, gamePath string) error {
+	if info, err := tty.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("the terminal UI needs a terminal to run on")
+	}
+
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return fmt.Errorf("failed to read the terminal settings: %v", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return fmt.Errorf("failed to set up the terminal: %v", err)
+	}
+	defer stty(tty, saved)
+
+	// Hide the cursor, and show it again on a cleared screen when done.
+	fmt.Fprint(tty, "\x1b[?25l\x1b[2J")
+	defer fmt.Fprint(tty, "\x1b[H\x1b[2J\x1b[?25h")
+
+	keys := make(chan string)
+	go readKeys(tty, keys)
+
+	ui := newTUI(gamePath)
+	colors := useColors(tty)
+	updates := make(chan positionAnalysis)
+	botMoves := make(chan positionAnalysis, 1)
+	var analyzed *GameState
+	var stop chan struct{}
+	defer func() {
+		if stop != nil {
+			close(stop)
+		}
+	}()
+
+	for {
+		state := ui.state()
+		if analyzed == nil || *analyzed != *state {
+			if stop != nil {
+				close(stop)
+				stop = nil
+			}
+			if !state.IsOver() {
+				stop = make(chan struct{})
+				go analyzePosition(*state, config, stop, updates)
+			}
+			analyzed = state
+		}
+
+		if ui.botRequested && !ui.thinking {
+			ui.botRequested = false
+			ui.thinking = true
+			go func(state GameState) {
+				botMoves <- positionAnalysis{state, bot(&state)}
+			}(*state)
+		}
+
+		// Clear every line as it is drawn over, and what is left below.
+		frame := strings.ReplaceAll(ui.render(colors), "\n", "\x1b[K\r\n")
+		fmt.Fprint(tty, "\x1b[H"+frame+"\x1b[J")
+
+		select {
+		case key, ok := <-keys:
+			if !ok || ui.handleKey(key) {
+				return nil
+			}
+		case update := <-updates:
+			if update.state == *ui.state() {
+				ui.analysis = update.analysis
+			}
+		case result := <-botMoves:
+			ui.thinking = false
+			if result.state == *ui.state() {
+				ui.play(*result.analysis.BestMove())
+			} else {
+				ui.message = "The position changed while the bot was thinking."
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTUIKeys(t *testing.T) {
+	ui := newTUI(filepath.Join(t.TempDir(), "game.txt"))
+
+	// Play the center square, then move the cursor up and left and play there.
+	for _, key := range []string{"enter", "k", "h", " "} {
+		if ui.handleKey(key) {
+			t.Fatal("Quit on", key)
+		}
+	}
+	if len(ui.moves()) != 2 || ui.moves()[0] != (Move{1, 1, 1, 1}) || ui.moves()[1] != (Move{1, 1, 0, 0}) {
+		t.Fatal("Expected the moves 1 1 1 1 and 1 1 0 0, got", ui.moves())
+	}
+
+	// X is sent to board 0 0, so the cursor's square is not legal.
+	ui.handleKey("enter")
+	if len(ui.moves()) != 2 || ui.message == "" {
+		t.Error("Played an illegal move:", ui.moves())
+	}
+
+	// Going back and playing another move replaces the rest of the game.
+	ui.handleKey(",")
+	if ui.shown() != 1 || len(ui.moves()) != 2 {
+		t.Error("Expected to show the first of two moves, got", ui.shown(), "of", len(ui.moves()))
+	}
+	ui.handleKey("j")
+	ui.handleKey("enter")
+	if ui.shown() != 2 || len(ui.moves()) != 2 || ui.moves()[1] != (Move{1, 1, 1, 0}) {
+		t.Error("Expected the second move to be replaced by 1 1 1 0, got", ui.moves())
+	}
+
+	ui.handleKey("<")
+	if ui.shown() != 0 || *ui.state() != *NewGame() {
+		t.Error("Expected to show the start of the game, got", ui.shown())
+	}
+	ui.handleKey(">")
+	if ui.shown() != 2 {
+		t.Error("Expected to show the end of the game, got", ui.shown())
+	}
+
+	ui.handleKey("g")
+	if !ui.botRequested {
+		t.Error("Expected the bot to be asked for a move")
+	}
+
+	if !ui.handleKey("q") || !ui.handleKey("ctrl-c") {
+		t.Error("Expected q and ctrl-c to quit")
+	}
+}
+
+func TestTUISaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.txt")
+	ui := newTUI(path)
+	ui.play(Move{1, 1, 1, 1})
+	ui.play(Move{1, 1, 0, 0})
+	ui.handleKey("s")
+
+	other := newTUI(path)
+	other.handleKey("o")
+	if len(other.moves()) != 2 || other.shown() != 2 || other.moves()[1] != (Move{1, 1, 0, 0}) {
+		t.Error("Expected to open the saved game, got", other.moves(), other.message)
+	}
+
+	missing := newTUI(filepath.Join(t.TempDir(), "missing.txt"))
+	missing.handleKey("o")
+	if len(missing.moves()) != 0 || missing.message == "" {
+		t.Error("Expected an error opening a missing game")
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	ui := newTUI("game.txt")
+	ui.play(Move{1, 1, 1, 1})
+	ui.analysis = &Analysis{Playouts: 1000, Candidates: []CandidateMove{{Move: Move{1, 1, 0, 0}, Visits: 600, WinRate: 0.5}}}
+
+	screen := ui.render(false)
+	for _, expected := range []string{"move 1 of 1. O to move on board 1 1.", "[X]", "1000 playouts", "1 1 0 0      600 visits", "1. *1 1 1 1", TUI_HELP} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("Expected %q on the screen:\n%s", expected, screen)
+		}
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	keys := make(chan string)
+	go readKeys(strings.NewReader("\x1b[A\x1b[Dq\r \x03"), keys)
+
+	var read []string
+	for key := range keys {
+		read = append(read, key)
+	}
+	if strings.Join(read, ",") != "up,left,q,enter, ,ctrl-c" {
+		t.Error("Unexpected keys", read)
+	}
+}
+
+func TestAnalyzePosition(t *testing.T) {
+	stop := make(chan struct{})
+	updates := make(chan positionAnalysis)
+	go analyzePosition(*NewGame(), DefaultSearchConfig, stop, updates)
+
+	select {
+	case update := <-updates:
+		if update.state != *NewGame() || update.analysis.Playouts == 0 {
+			t.Error("Unexpected update", update.analysis)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The analysis never reported")
+	}
+	close(stop)
+}
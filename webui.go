@@ -0,0 +1,189 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webUI is the page of the browser UI, with its styles and scripts inline.
+//
+//go:embed webui.html
+var webUI []byte
+
+// GameView is a position of a game as the browser UI shows it.
+type GameView struct {
+	Moves      []Move    `json:"moves"`
+	Rows       []string  `json:"rows"`             // The squares, as in HackerRank's format
+	Boards     [3]string `json:"boards"`           // The winner of every board ('-' for none)
+	Player     string    `json:"player"`           // The player to move, "X" or "O"
+	ForcedX    int       `json:"forcedX"`          // The board the player to move is sent to,
+	ForcedY    int       `json:"forcedY"`          // -1 -1 if they may play on any board
+	ValidMoves []Move    `json:"validMoves"`       // Empty once the game is over
+	Result     string    `json:"result,omitempty"` // "X", "O" or "draw" once the game is over
+}
+
+// newGameView returns the view of the position after moves, or an error if
+// one of the moves is not legal.
+func newGameView(moves []Move) (*GameView, error) {
+	positions, err := GameRecord{Moves: moves}.Positions()
+	if err != nil {
+		return nil, err
+	}
+	state := positions[len(positions)-1]
+
+	view := &GameView{Moves: moves, Player: string(rune(PlayerMark(state.Player))), ValidMoves: []Move{}}
+	if view.Moves == nil {
+		view.Moves = []Move{}
+	}
+	view.ForcedX, view.ForcedY, _ = state.ForcedBoard()
+
+	for row := 0; row < 9; row++ {
+		var line []byte
+		for column := 0; column < 9; column++ {
+			line = append(line, byte(state.Board[row/3][column/3][row%3][column%3]))
+		}
+		view.Rows = append(view.Rows, string(line))
+	}
+	for x := 0; x < 3; x++ {
+		var line []byte
+		for y := 0; y < 3; y++ {
+			line = append(line, byte(state.Board[x][y].HasWinner()))
+		}
+		view.Boards[x] = string(line)
+	}
+
+	switch winner := state.Winner(); {
+	case winner != EMPTY:
+		view.Result = string(rune(winner))
+	case state.IsOver():
+		view.Result = "draw"
+	default:
+		for _, move := range state.ValidMoves() {
+			view.ValidMoves = append(view.ValidMoves, *move)
+		}
+	}
+
+	return view, nil
+}
+
+// webRequest is what the browser UI POSTs: the moves of the game so far,
+// and for /bot-move the bot to move and how many seconds it may think.
+type webRequest struct {
+	Moves []Move  `json:"moves"`
+	Bot   string  `json:"bot"`
+	Time  float64 `json:"time"`
+}
+
+// readWebRequest decodes the webRequest POSTed in r, responding with
+// an error and returning nil if it cannot.
+func readWebRequest(w http.ResponseWriter, r *http.Request) *webRequest {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST the moves of the game", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	var request webRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	return &request
+}
+
+// handleWebUI serves the page of the browser UI.
+func handleWebUI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webUI)
+}
+
+// handleBots responds with the names of the bots the browser UI can play against.
+func handleBots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BotNames())
+}
+
+// handleGame responds with the GameView of the moves POSTed as a webRequest.
+func handleGame(w http.ResponseWriter, r *http.Request) {
+	request := readWebRequest(w, r)
+	if request == nil {
+		return
+	}
+
+	view, err := newGameView(request.Moves)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// botMoveResponse is the move a bot made for the browser UI, with the
+// Analysis of its search and the game after the move.
+type botMoveResponse struct {
+	Move     Move      `json:"move"`
+	Analysis *Analysis `json:"analysis"`
+	Game     *GameView `json:"game"`
+}
+
+// handleBotMove has the bot named in the webRequest POSTed make the next
+// move of the game, thinking for at most TIME_TO_THINK seconds.
+func handleBotMove(w http.ResponseWriter, r *http.Request) {
+	request := readWebRequest(w, r)
+	if request == nil {
+		return
+	}
+
+	newAnalyst, ok := Analysts[request.Bot]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown bot %q", request.Bot), http.StatusBadRequest)
+		return
+	}
+	if request.Time <= 0 || request.Time > TIME_TO_THINK {
+		http.Error(w, "time must be between 0 and TIME_TO_THINK seconds", http.StatusBadRequest)
+		return
+	}
+
+	positions, err := GameRecord{Moves: request.Moves}.Positions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state := positions[len(positions)-1]
+	if state.IsOver() {
+		http.Error(w, "the game is already over", http.StatusBadRequest)
+		return
+	}
+
+	config := DefaultSearchConfig
+	config.ThinkTime = time.Duration(request.Time * float64(time.Second))
+	analysis := newAnalyst(config)(&state)
+	move := analysis.BestMove()
+	if move == nil {
+		// The bot gave up before it found a move.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("the %s bot found no move", request.Bot)})
+		return
+	}
+
+	response := botMoveResponse{Move: *move, Analysis: analysis}
+	if response.Game, err = newGameView(append(request.Moves, *move)); err != nil {
+		// Only a broken bot makes an illegal move.
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// postWeb POSTs request as JSON to handler, returning the recorded response.
+func postWeb(t *testing.T, handler http.HandlerFunc, path string, request webRequest) *httptest.ResponseRecorder {
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal("Failed to encode the request:", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("POST", path, strings.NewReader(string(body))))
+	return recorder
+}
+
+func TestHandleWebUI(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	handleWebUI(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+		t.Fatal("Expected the page of the UI, got", recorder.Code, recorder.Header())
+	}
+	if !strings.Contains(recorder.Body.String(), "/bot-move") {
+		t.Error("Expected the page to ask the server for bot moves")
+	}
+
+	recorder = httptest.NewRecorder()
+	handleWebUI(recorder, httptest.NewRequest("GET", "/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Error("Expected other paths not to be found, got", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handleBots(recorder, httptest.NewRequest("GET", "/bots", nil))
+	var bots []string
+	if err := json.NewDecoder(recorder.Body).Decode(&bots); err != nil || len(bots) != len(Analysts) {
+		t.Error("Expected the names of all the bots, got", bots, err)
+	}
+}
+
+func TestHandleGame(t *testing.T) {
+	recorder := postWeb(t, handleGame, "/game", webRequest{})
+	var view GameView
+	if err := json.NewDecoder(recorder.Body).Decode(&view); err != nil {
+		t.Fatal("Failed to decode the game:", err)
+	}
+	if view.Player != "X" || view.ForcedX != -1 || len(view.ValidMoves) != 81 || view.Result != "" {
+		t.Error("Unexpected view of a new game:", view)
+	}
+
+	recorder = postWeb(t, handleGame, "/game", webRequest{Moves: []Move{{1, 1, 0, 2}}})
+	if err := json.NewDecoder(recorder.Body).Decode(&view); err != nil {
+		t.Fatal("Failed to decode the game:", err)
+	}
+	if view.Player != "O" || view.ForcedX != 0 || view.ForcedY != 2 || len(view.ValidMoves) != 9 || view.Rows[3][5] != 'X' {
+		t.Error("Unexpected view after one move:", view)
+	}
+
+	recorder = postWeb(t, handleGame, "/game", webRequest{Moves: []Move{{1, 1, 0, 2}, {1, 1, 0, 0}}})
+	if recorder.Code != http.StatusBadRequest {
+		t.Error("Expected an illegal move to be rejected, got", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handleGame(recorder, httptest.NewRequest("GET", "/game", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Error("Expected only POST to be allowed, got", recorder.Code)
+	}
+}
+
+func TestHandleBotMove(t *testing.T) {
+	moves := []Move{{1, 1, 0, 2}}
+	recorder := postWeb(t, handleBotMove, "/bot-move", webRequest{Moves: moves, Bot: "random", Time: 0.1})
+	if recorder.Code != http.StatusOK {
+		t.Fatal("Expected status 200, got", recorder.Code, recorder.Body.String())
+	}
+
+	var response botMoveResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal("Failed to decode the move:", err)
+	}
+	if response.Move.BoardX != 0 || response.Move.BoardY != 2 || len(response.Game.Moves) != 2 || response.Game.Player != "X" {
+		t.Error("Expected a move on the forced board, got", response.Move, response.Game)
+	}
+	if response.Analysis == nil || len(response.Analysis.Candidates) == 0 {
+		t.Error("Expected the analysis of the move, got", response.Analysis)
+	}
+
+	// Play a whole game, always taking the first valid move.
+	state := *NewGame()
+	var finished []Move
+	for !state.IsOver() {
+		move := state.ValidMoves()[0]
+		finished = append(finished, *move)
+		state.Play(move)
+	}
+
+	for name, request := range map[string]webRequest{
+		"unknown bot":   {Moves: moves, Bot: "nobody", Time: 0.1},
+		"no time":       {Moves: moves, Bot: "random"},
+		"too long":      {Moves: moves, Bot: "random", Time: 100},
+		"illegal move":  {Moves: []Move{{1, 1, 0, 2}, {1, 1, 0, 0}}, Bot: "random", Time: 0.1},
+		"finished game": {Moves: finished, Bot: "random", Time: 0.1},
+	} {
+		if recorder := postWeb(t, handleBotMove, "/bot-move", request); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected a request with %s to be rejected, got %d", name, recorder.Code)
+		}
+	}
+
+	// A bot which finds no move is an error of the server's.
+	Analysts["nothing"] = func(config SearchConfig) Analyst {
+		return func(state *GameState) *Analysis { return &Analysis{} }
+	}
+	defer delete(Analysts, "nothing")
+	recorder = postWeb(t, handleBotMove, "/bot-move", webRequest{Moves: moves, Bot: "nothing", Time: 0.1})
+	var failure struct{ Error string }
+	if err := json.NewDecoder(recorder.Body).Decode(&failure); recorder.Code != http.StatusInternalServerError || err != nil || failure.Error == "" {
+		t.Error("Expected a JSON error from a bot without a move, got", recorder.Code, failure, err)
+	}
+}